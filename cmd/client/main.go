@@ -3,10 +3,14 @@ package main
 import (
 	// "github.com/epk/mmap-rpc/gen/cache"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/epk/mmap-rpc/gen/cache"
 	"github.com/epk/mmap-rpc/pkg/client"
+	"github.com/epk/mmap-rpc/pkg/metadata"
 )
 
 func main() {
@@ -21,13 +25,14 @@ func main() {
 	}
 	cc := cache.NewMmapRPCCacheClient(c)
 
-	r, err := cc.Get(context.Background(), &cache.GetRequest{
+	var header metadata.MD
+	r, err := cc.Get(metadata.AppendToOutgoingContext(context.Background(), "request-id", "42"), &cache.GetRequest{
 		Key: "foo",
-	})
+	}, client.Header(&header))
 	if err != nil {
 		fmt.Println("[client] Get error:", err)
 	} else {
-		fmt.Printf("[client] Get response: %v\n", r)
+		fmt.Printf("[client] Get response: %v, request ID: %s\n", r, header.Get("request-id"))
 	}
 
 	rr, err := cc.Set(context.Background(), &cache.SetRequest{
@@ -48,4 +53,77 @@ func main() {
 	} else {
 		fmt.Printf("[client] Get response: %v\n", rrr)
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	watch, err := cc.Watch(ctx, &cache.WatchRequest{
+		Keys: []string{"foo"},
+	})
+	if err != nil {
+		fmt.Println("[client] Watch error:", err)
+		return
+	}
+	event, err := watch.Recv()
+	if err != nil {
+		fmt.Println("[client] Watch error:", err)
+		return
+	}
+	fmt.Printf("[client] Watch event: %v\n", event)
+
+	if _, err := cc.Set(context.Background(), &cache.SetRequest{
+		Key:   "foo",
+		Value: "baz",
+	}); err != nil {
+		fmt.Println("[client] Set error:", err)
+		return
+	}
+	event, err = watch.Recv()
+	if err != nil {
+		fmt.Println("[client] Watch error:", err)
+		return
+	}
+	fmt.Printf("[client] Watch event: %v\n", event)
+
+	load, err := cc.Load(context.Background())
+	if err != nil {
+		fmt.Println("[client] Load error:", err)
+		return
+	}
+	for _, value := range []string{"one", "two", "three"} {
+		if err := load.Send(&cache.SetRequest{Key: "foo", Value: value}); err != nil {
+			fmt.Println("[client] Load error:", err)
+			return
+		}
+	}
+	summary, err := load.CloseAndRecv()
+	if err != nil {
+		fmt.Println("[client] Load error:", err)
+		return
+	}
+	fmt.Printf("[client] Load response: %v\n", summary)
+
+	lookup, err := cc.Lookup(context.Background())
+	if err != nil {
+		fmt.Println("[client] Lookup error:", err)
+		return
+	}
+	for _, key := range []string{"foo", "bar"} {
+		if err := lookup.Send(&cache.GetRequest{Key: key}); err != nil {
+			fmt.Println("[client] Lookup error:", err)
+			return
+		}
+		r, err := lookup.Recv()
+		if err != nil {
+			fmt.Println("[client] Lookup error:", err)
+			return
+		}
+		fmt.Printf("[client] Lookup response: %v\n", r)
+	}
+	if err := lookup.CloseSend(); err != nil {
+		fmt.Println("[client] Lookup error:", err)
+		return
+	}
+	if _, err := lookup.Recv(); !errors.Is(err, io.EOF) {
+		fmt.Println("[client] Lookup error:", err)
+	}
 }
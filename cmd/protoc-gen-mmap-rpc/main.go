@@ -0,0 +1,29 @@
+// protoc-gen-mmap-rpc is a protoc plugin that generates mmap-rpc client and
+// server stubs for the services defined in .proto files.
+//
+// Usage:
+//
+//	protoc --go_out=gen --go_opt=paths=source_relative \
+//	       --mmap-rpc_out=gen --mmap-rpc_opt=paths=source_relative \
+//	       cache/cache.proto
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			if err := generateFile(gen, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	protoPackage   = protogen.GoImportPath("google.golang.org/protobuf/proto")
+	clientPackage  = protogen.GoImportPath("github.com/epk/mmap-rpc/pkg/client")
+	serverPackage  = protogen.GoImportPath("github.com/epk/mmap-rpc/pkg/server")
+	codesPackage   = protogen.GoImportPath("github.com/epk/mmap-rpc/pkg/codes")
+	statusPackage  = protogen.GoImportPath("github.com/epk/mmap-rpc/pkg/status")
+)
+
+// generateFile generates a _mmap-rpc.pb.go file containing mmap-rpc service definitions.
+func generateFile(gen *protogen.Plugin, file *protogen.File) error {
+	if len(file.Services) == 0 {
+		return nil
+	}
+
+	filename := file.GeneratedFilenamePrefix + "_mmap-rpc.pb.go"
+	g := gen.NewGeneratedFile(filename, file.GoImportPath)
+
+	g.P("// Code generated by protoc-gen-mmap-rpc. DO NOT EDIT.")
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+
+	generateMethodNames(g, file)
+	for _, service := range file.Services {
+		generateClient(g, service)
+		generateServer(g, service)
+	}
+
+	return nil
+}
+
+// fullMethodNameConst returns the name of the constant holding the fully qualified method name.
+func fullMethodNameConst(method *protogen.Method) string {
+	return fmt.Sprintf("_%s_%s_FullMethodName", method.Parent.GoName, method.GoName)
+}
+
+func generateMethodNames(g *protogen.GeneratedFile, file *protogen.File) {
+	g.P("const (")
+	for _, service := range file.Services {
+		for _, method := range service.Methods {
+			fmName := fmt.Sprintf("/%s/%s", service.Desc.FullName(), method.Desc.Name())
+			g.P(fullMethodNameConst(method), " = ", fmt.Sprintf("%q", fmName))
+		}
+	}
+	g.P(")")
+	g.P()
+}
+
+func generateClient(g *protogen.GeneratedFile, service *protogen.Service) {
+	clientName := "MmapRPC" + service.GoName + "Client"
+	structName := "mmapRPC" + service.GoName + "Client"
+
+	g.P("// ", clientName, " is the client API for ", service.GoName, " service.")
+	g.P("type ", clientName, " interface {")
+	for _, method := range service.Methods {
+		g.P(clientSignature(g, method))
+	}
+	g.P("}")
+	g.P()
+
+	g.P("type ", structName, " struct {")
+	g.P("client *", g.QualifiedGoIdent(clientPackage.Ident("Client")))
+	g.P("}")
+	g.P()
+
+	for _, method := range service.Methods {
+		if isStreaming(method) {
+			generateStreamClient(g, structName, method)
+			continue
+		}
+		g.P("func (c *", structName, ") ", clientSignature(g, method), " {")
+		g.P("out := &", g.QualifiedGoIdent(method.Output.GoIdent), "{}")
+		g.P("if err := c.client.Invoke(ctx, ", fullMethodNameConst(method), ", in, out, opts...); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return out, nil")
+		g.P("}")
+		g.P()
+	}
+
+	g.P("// New", clientName, " creates a new ", clientName)
+	g.P("func New", clientName, "(client *", g.QualifiedGoIdent(clientPackage.Ident("Client")), ") ", clientName, " {")
+	g.P("return &", structName, "{")
+	g.P("client: client,")
+	g.P("}")
+	g.P("}")
+	g.P()
+}
+
+func clientSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
+	ctx := "ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context"))
+	opts := "opts ..." + g.QualifiedGoIdent(clientPackage.Ident("CallOption"))
+	if method.Desc.IsStreamingClient() {
+		return method.GoName + "(" + ctx + ", " + opts + ") (" + streamInterfaceName(method, "Client") + ", error)"
+	}
+	out := "*" + g.QualifiedGoIdent(method.Output.GoIdent)
+	if method.Desc.IsStreamingServer() {
+		out = streamInterfaceName(method, "Client")
+	}
+	return method.GoName + "(" + ctx +
+		", in *" + g.QualifiedGoIdent(method.Input.GoIdent) +
+		", " + opts + ") (" + out + ", error)"
+}
+
+// isStreaming reports whether the client or the server of method sends a stream of messages.
+func isStreaming(method *protogen.Method) bool {
+	return method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer()
+}
+
+// streamInterfaceName returns the name of the interface of the client or server side of a stream.
+func streamInterfaceName(method *protogen.Method, side string) string {
+	return "MmapRPC" + method.Parent.GoName + "_" + method.GoName + side
+}
+
+// streamStructName returns the name of the struct implementing streamInterfaceName.
+func streamStructName(method *protogen.Method, side string) string {
+	return "mmapRPC" + method.Parent.GoName + method.GoName + side
+}
+
+// generateStreamClient generates the client method of a streaming RPC and the type of the stream
+// it returns.
+func generateStreamClient(g *protogen.GeneratedFile, structName string, method *protogen.Method) {
+	interfaceName := streamInterfaceName(method, "Client")
+	streamName := streamStructName(method, "Client")
+	clientStreams, serverStreams := method.Desc.IsStreamingClient(), method.Desc.IsStreamingServer()
+
+	g.P("func (c *", structName, ") ", clientSignature(g, method), " {")
+	if clientStreams {
+		g.P("stream, err := c.client.NewStream(ctx, &", g.QualifiedGoIdent(clientPackage.Ident("StreamDesc")), "{")
+		if serverStreams {
+			g.P("ServerStreams: true,")
+		}
+		g.P("ClientStreams: true,")
+		g.P("}, ", fullMethodNameConst(method), ", opts...)")
+	} else {
+		g.P("stream, err := c.client.NewServerStream(ctx, ", fullMethodNameConst(method), ", in, opts...)")
+	}
+	g.P("if err != nil {")
+	g.P("return nil, err")
+	g.P("}")
+	g.P("return &", streamName, "{stream: stream}, nil")
+	g.P("}")
+	g.P()
+
+	g.P("// ", interfaceName, " is the client API for the ", method.GoName, " stream.")
+	g.P("type ", interfaceName, " interface {")
+	if clientStreams {
+		g.P("Send(*", g.QualifiedGoIdent(method.Input.GoIdent), ") error")
+	}
+	switch {
+	case clientStreams && !serverStreams:
+		g.P("CloseAndRecv() (*", g.QualifiedGoIdent(method.Output.GoIdent), ", error)")
+	case clientStreams:
+		g.P("Recv() (*", g.QualifiedGoIdent(method.Output.GoIdent), ", error)")
+		g.P("CloseSend() error")
+	default:
+		g.P("Recv() (*", g.QualifiedGoIdent(method.Output.GoIdent), ", error)")
+	}
+	g.P("}")
+	g.P()
+
+	g.P("type ", streamName, " struct {")
+	g.P("stream *", g.QualifiedGoIdent(clientPackage.Ident("ClientStream")))
+	g.P("}")
+	g.P()
+
+	if clientStreams {
+		g.P("func (x *", streamName, ") Send(m *", g.QualifiedGoIdent(method.Input.GoIdent), ") error {")
+		g.P("return x.stream.Send(m)")
+		g.P("}")
+		g.P()
+	}
+
+	if clientStreams && !serverStreams {
+		g.P("func (x *", streamName, ") CloseAndRecv() (*", g.QualifiedGoIdent(method.Output.GoIdent), ", error) {")
+		g.P("if err := x.stream.CloseSend(); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+	} else {
+		g.P("func (x *", streamName, ") Recv() (*", g.QualifiedGoIdent(method.Output.GoIdent), ", error) {")
+	}
+	g.P("m := &", g.QualifiedGoIdent(method.Output.GoIdent), "{}")
+	g.P("if err := x.stream.Recv(m); err != nil {")
+	g.P("return nil, err")
+	g.P("}")
+	g.P("return m, nil")
+	g.P("}")
+	g.P()
+
+	if clientStreams && serverStreams {
+		g.P("func (x *", streamName, ") CloseSend() error {")
+		g.P("return x.stream.CloseSend()")
+		g.P("}")
+		g.P()
+	}
+}
+
+func generateServer(g *protogen.GeneratedFile, service *protogen.Service) {
+	serverName := "MmapRPC" + service.GoName + "Server"
+
+	g.P("// ", serverName, " is the server API for ", service.GoName, " service.")
+	g.P("type ", serverName, " interface {")
+	for _, method := range service.Methods {
+		if method.Desc.IsStreamingClient() {
+			g.P(method.GoName, "(", streamInterfaceName(method, "Server"), ") error")
+			continue
+		}
+		if method.Desc.IsStreamingServer() {
+			g.P(method.GoName, "(*", g.QualifiedGoIdent(method.Input.GoIdent), ", ", streamInterfaceName(method, "Server"), ") error")
+			continue
+		}
+		g.P(method.GoName, "(", g.QualifiedGoIdent(contextPackage.Ident("Context")),
+			", *", g.QualifiedGoIdent(method.Input.GoIdent),
+			") (*", g.QualifiedGoIdent(method.Output.GoIdent), ", error)")
+	}
+	g.P("}")
+	g.P()
+
+	g.P("// Register", serverName, " registers the ", serverName, " with the given server.")
+	g.P("func Register", serverName, "(s *", g.QualifiedGoIdent(serverPackage.Ident("Server")), ", srv ", serverName, ") {")
+	for i, method := range service.Methods {
+		if i > 0 {
+			g.P()
+		}
+		if isStreaming(method) {
+			generateRegisterStream(g, method)
+			continue
+		}
+		g.P("s.RegisterHandler(", fullMethodNameConst(method), ", func(ctx ", g.QualifiedGoIdent(contextPackage.Ident("Context")), ", data []byte) ([]byte, error) {")
+		g.P("return ", g.QualifiedGoIdent(serverPackage.Ident("HandleRequest")), "(ctx, data, srv.", method.GoName, ", &", g.QualifiedGoIdent(method.Input.GoIdent), "{})")
+		g.P("})")
+	}
+	g.P("}")
+	g.P()
+
+	for _, method := range service.Methods {
+		if isStreaming(method) {
+			generateStreamServer(g, method)
+		}
+	}
+}
+
+// generateRegisterStream generates the registration of the handler of a streaming RPC.
+func generateRegisterStream(g *protogen.GeneratedFile, method *protogen.Method) {
+	g.P("s.RegisterStream(&", g.QualifiedGoIdent(serverPackage.Ident("StreamDesc")), "{")
+	g.P("MethodName: ", fullMethodNameConst(method), ",")
+	g.P("Handler: func(ctx ", g.QualifiedGoIdent(contextPackage.Ident("Context")),
+		", data []byte, stream *", g.QualifiedGoIdent(serverPackage.Ident("ServerStream")), ") error {")
+	if method.Desc.IsStreamingClient() {
+		g.P("return srv.", method.GoName, "(&", streamStructName(method, "Server"), "{stream: stream})")
+	} else {
+		g.P("in := &", g.QualifiedGoIdent(method.Input.GoIdent), "{}")
+		g.P("if err := ", g.QualifiedGoIdent(protoPackage.Ident("Unmarshal")), "(data, in); err != nil {")
+		g.P("return ", g.QualifiedGoIdent(statusPackage.Ident("Errorf")), "(", g.QualifiedGoIdent(codesPackage.Ident("InvalidArgument")), `, "failed to unmarshal request: %v", err)`)
+		g.P("}")
+		g.P("return srv.", method.GoName, "(in, &", streamStructName(method, "Server"), "{stream: stream})")
+	}
+	g.P("},")
+	if method.Desc.IsStreamingServer() {
+		g.P("ServerStreams: true,")
+	}
+	if method.Desc.IsStreamingClient() {
+		g.P("ClientStreams: true,")
+	}
+	g.P("})")
+}
+
+// generateStreamServer generates the type of the stream passed to the server implementation of a
+// streaming RPC.
+func generateStreamServer(g *protogen.GeneratedFile, method *protogen.Method) {
+	interfaceName := streamInterfaceName(method, "Server")
+	streamName := streamStructName(method, "Server")
+	clientStreams, serverStreams := method.Desc.IsStreamingClient(), method.Desc.IsStreamingServer()
+
+	// A server that does not stream sends its single message with SendAndClose.
+	send := "Send"
+	if !serverStreams {
+		send = "SendAndClose"
+	}
+
+	g.P("// ", interfaceName, " is the server API for the ", method.GoName, " stream.")
+	g.P("type ", interfaceName, " interface {")
+	g.P(send, "(*", g.QualifiedGoIdent(method.Output.GoIdent), ") error")
+	if clientStreams {
+		g.P("Recv() (*", g.QualifiedGoIdent(method.Input.GoIdent), ", error)")
+	}
+	g.P("Context() ", g.QualifiedGoIdent(contextPackage.Ident("Context")))
+	g.P("}")
+	g.P()
+
+	g.P("type ", streamName, " struct {")
+	g.P("stream *", g.QualifiedGoIdent(serverPackage.Ident("ServerStream")))
+	g.P("}")
+	g.P()
+
+	g.P("func (x *", streamName, ") ", send, "(m *", g.QualifiedGoIdent(method.Output.GoIdent), ") error {")
+	g.P("return x.stream.Send(m)")
+	g.P("}")
+	g.P()
+
+	if clientStreams {
+		g.P("func (x *", streamName, ") Recv() (*", g.QualifiedGoIdent(method.Input.GoIdent), ", error) {")
+		g.P("m := &", g.QualifiedGoIdent(method.Input.GoIdent), "{}")
+		g.P("if err := x.stream.Recv(m); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return m, nil")
+		g.P("}")
+		g.P()
+	}
+
+	g.P("func (x *", streamName, ") Context() ", g.QualifiedGoIdent(contextPackage.Ident("Context")), " {")
+	g.P("return x.stream.Context()")
+	g.P("}")
+	g.P()
+}
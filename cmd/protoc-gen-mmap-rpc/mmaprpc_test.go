@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/epk/mmap-rpc/gen/cache"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the generated code")
+
+// TestGolden generates the code for cache/cache.proto and compares it with the checked-in
+// gen/cache/cache_mmap-rpc.pb.go.
+func TestGolden(t *testing.T) {
+	file := protodesc.ToFileDescriptorProto(cache.File_cache_cache_proto)
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		Parameter:      proto.String("paths=source_relative"),
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	}
+
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		if err := generateFile(gen, f); err != nil {
+			t.Fatalf("failed to generate %s: %v", f.Desc.Path(), err)
+		}
+	}
+
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatalf("plugin reported an error: %s", resp.GetError())
+	}
+	if len(resp.File) != 1 {
+		t.Fatalf("got %d generated files, want 1", len(resp.File))
+	}
+
+	generated := resp.File[0]
+	if got, want := generated.GetName(), "cache/cache_mmap-rpc.pb.go"; got != want {
+		t.Fatalf("got generated file %s, want %s", got, want)
+	}
+
+	golden := filepath.Join("..", "..", "gen", generated.GetName())
+	if *update {
+		if err := os.WriteFile(golden, []byte(generated.GetContent()), 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if got := []byte(generated.GetContent()); !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s, run go test -update to rewrite it\ngot:\n%s", golden, got)
+	}
+}
+
+// TestNoServices checks that no file is generated for a .proto file without services.
+func TestNoServices(t *testing.T) {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("empty/empty.proto"),
+		Package: proto.String("empty"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{
+			GoPackage: proto.String("example.com/empty"),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Empty")},
+		},
+	}
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	}
+
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatalf("failed to create plugin: %v", err)
+	}
+	for _, f := range gen.Files {
+		if err := generateFile(gen, f); err != nil {
+			t.Fatalf("failed to generate %s: %v", f.Desc.Path(), err)
+		}
+	}
+	if files := gen.Response().File; len(files) != 0 {
+		t.Errorf("got %d generated files, want none", len(files))
+	}
+}
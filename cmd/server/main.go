@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/pkg/metadata"
 	"github.com/epk/mmap-rpc/pkg/server"
 )
 
+// value is the value of every key, guarded by stub.mu.
 var value = "😄😄😄😄😄😄😄😄😄😄😄😄😄😄😄😄"
 
 func main() {
@@ -18,35 +25,134 @@ func main() {
 
 	srv := server.Server{}
 
-	cache.RegisterMmapRPCCacheServer(&srv, &stub{})
+	cache.RegisterMmapRPCCacheServer(&srv, &stub{watchers: make(map[chan *cache.WatchEvent]struct{})})
 	go func() {
-		if err := srv.ListenAndServe("/tmp/mmap/server.sock", "/tmp/mmap/"); err != nil {
+		if err := srv.ListenAndServe("/tmp/mmap/server.sock", "/tmp/mmap/"); err != nil && !errors.Is(err, server.ErrServerClosed) {
 			panic(err)
 		}
 	}()
 
 	<-shutDown
-	srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		fmt.Println("[server] shutdown:", err)
+	}
 }
 
 var _ cache.MmapRPCCacheServer = (*stub)(nil)
 
 type stub struct {
+	mu sync.Mutex
+	// watchers receive the events of the Set calls.
+	watchers map[chan *cache.WatchEvent]struct{}
 }
 
 func (s *stub) Get(ctx context.Context, in *cache.GetRequest) (*cache.GetResponse, error) {
 	fmt.Println("[server] Get request for key:", in.Key)
 
+	// Echo the request ID of the client, if it sent one.
+	if md, ok := metadata.FromIncomingContext(ctx); ok && md.Get("request-id") != nil {
+		if err := server.SetHeader(ctx, metadata.MD{"request-id": md.Get("request-id")}); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	current := value
+	s.mu.Unlock()
+
 	return &cache.GetResponse{
-		Value: value,
+		Value: current,
 		Found: true,
 	}, nil
 }
 func (s *stub) Set(ctx context.Context, in *cache.SetRequest) (*cache.SetResponse, error) {
 	fmt.Println("[server] Set request for key:", in.Key, "value:", in.Value)
 
+	s.mu.Lock()
 	value = in.Value
+	for ch := range s.watchers {
+		select {
+		case ch <- &cache.WatchEvent{Key: in.Key, Value: in.Value}:
+		default:
+			// The watcher is too slow, it misses the event.
+		}
+	}
+	s.mu.Unlock()
+
 	return &cache.SetResponse{
 		Success: true,
 	}, nil
 }
+
+func (s *stub) Watch(in *cache.WatchRequest, stream cache.MmapRPCCache_WatchServer) error {
+	fmt.Println("[server] Watch request for keys:", in.Keys)
+
+	ch := make(chan *cache.WatchEvent, 16)
+	s.mu.Lock()
+	current := value
+	s.watchers[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, ch)
+		s.mu.Unlock()
+	}()
+
+	for _, key := range in.Keys {
+		if err := stream.Send(&cache.WatchEvent{Key: key, Value: current}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case event := <-ch:
+			if !slices.Contains(in.Keys, event.Key) {
+				continue
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (s *stub) Load(stream cache.MmapRPCCache_LoadServer) error {
+	var count int64
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&cache.LoadResponse{Count: count})
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := s.Set(stream.Context(), in); err != nil {
+			return err
+		}
+		count++
+	}
+}
+
+func (s *stub) Lookup(stream cache.MmapRPCCache_LookupServer) error {
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resp, err := s.Get(stream.Context(), in)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
@@ -1,45 +1,152 @@
+// Code generated by protoc-gen-mmap-rpc. DO NOT EDIT.
+// source: cache/cache.proto
+
 package cache
 
 import (
 	context "context"
-
-	"google.golang.org/protobuf/proto"
-
-	"github.com/epk/mmap-rpc/pkg/client"
-	"github.com/epk/mmap-rpc/pkg/server"
+	client "github.com/epk/mmap-rpc/pkg/client"
+	codes "github.com/epk/mmap-rpc/pkg/codes"
+	server "github.com/epk/mmap-rpc/pkg/server"
+	status "github.com/epk/mmap-rpc/pkg/status"
+	proto "google.golang.org/protobuf/proto"
 )
 
 const (
-	_Cache_Get_FullMethodName = "/cache.Cache/Get"
-	_Cache_Set_FullMethodName = "/cache.Cache/Set"
+	_Cache_Get_FullMethodName    = "/cache.Cache/Get"
+	_Cache_Set_FullMethodName    = "/cache.Cache/Set"
+	_Cache_Watch_FullMethodName  = "/cache.Cache/Watch"
+	_Cache_Load_FullMethodName   = "/cache.Cache/Load"
+	_Cache_Lookup_FullMethodName = "/cache.Cache/Lookup"
 )
 
 // MmapRPCCacheClient is the client API for Cache service.
 type MmapRPCCacheClient interface {
-	Get(ctx context.Context, in *GetRequest) (*GetResponse, error)
-	Set(ctx context.Context, in *SetRequest) (*SetResponse, error)
+	Get(ctx context.Context, in *GetRequest, opts ...client.CallOption) (*GetResponse, error)
+	Set(ctx context.Context, in *SetRequest, opts ...client.CallOption) (*SetResponse, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...client.CallOption) (MmapRPCCache_WatchClient, error)
+	Load(ctx context.Context, opts ...client.CallOption) (MmapRPCCache_LoadClient, error)
+	Lookup(ctx context.Context, opts ...client.CallOption) (MmapRPCCache_LookupClient, error)
 }
 
 type mmapRPCCacheClient struct {
 	client *client.Client
 }
 
-func (c *mmapRPCCacheClient) Get(ctx context.Context, in *GetRequest) (*GetResponse, error) {
+func (c *mmapRPCCacheClient) Get(ctx context.Context, in *GetRequest, opts ...client.CallOption) (*GetResponse, error) {
 	out := &GetResponse{}
-	if err := c.client.Invoke(ctx, _Cache_Get_FullMethodName, in, out); err != nil {
+	if err := c.client.Invoke(ctx, _Cache_Get_FullMethodName, in, out, opts...); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *mmapRPCCacheClient) Set(ctx context.Context, in *SetRequest) (*SetResponse, error) {
+func (c *mmapRPCCacheClient) Set(ctx context.Context, in *SetRequest, opts ...client.CallOption) (*SetResponse, error) {
 	out := &SetResponse{}
-	if err := c.client.Invoke(ctx, _Cache_Set_FullMethodName, in, out); err != nil {
+	if err := c.client.Invoke(ctx, _Cache_Set_FullMethodName, in, out, opts...); err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
+func (c *mmapRPCCacheClient) Watch(ctx context.Context, in *WatchRequest, opts ...client.CallOption) (MmapRPCCache_WatchClient, error) {
+	stream, err := c.client.NewServerStream(ctx, _Cache_Watch_FullMethodName, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &mmapRPCCacheWatchClient{stream: stream}, nil
+}
+
+// MmapRPCCache_WatchClient is the client API for the Watch stream.
+type MmapRPCCache_WatchClient interface {
+	Recv() (*WatchEvent, error)
+}
+
+type mmapRPCCacheWatchClient struct {
+	stream *client.ClientStream
+}
+
+func (x *mmapRPCCacheWatchClient) Recv() (*WatchEvent, error) {
+	m := &WatchEvent{}
+	if err := x.stream.Recv(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mmapRPCCacheClient) Load(ctx context.Context, opts ...client.CallOption) (MmapRPCCache_LoadClient, error) {
+	stream, err := c.client.NewStream(ctx, &client.StreamDesc{
+		ClientStreams: true,
+	}, _Cache_Load_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &mmapRPCCacheLoadClient{stream: stream}, nil
+}
+
+// MmapRPCCache_LoadClient is the client API for the Load stream.
+type MmapRPCCache_LoadClient interface {
+	Send(*SetRequest) error
+	CloseAndRecv() (*LoadResponse, error)
+}
+
+type mmapRPCCacheLoadClient struct {
+	stream *client.ClientStream
+}
+
+func (x *mmapRPCCacheLoadClient) Send(m *SetRequest) error {
+	return x.stream.Send(m)
+}
+
+func (x *mmapRPCCacheLoadClient) CloseAndRecv() (*LoadResponse, error) {
+	if err := x.stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := &LoadResponse{}
+	if err := x.stream.Recv(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mmapRPCCacheClient) Lookup(ctx context.Context, opts ...client.CallOption) (MmapRPCCache_LookupClient, error) {
+	stream, err := c.client.NewStream(ctx, &client.StreamDesc{
+		ServerStreams: true,
+		ClientStreams: true,
+	}, _Cache_Lookup_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &mmapRPCCacheLookupClient{stream: stream}, nil
+}
+
+// MmapRPCCache_LookupClient is the client API for the Lookup stream.
+type MmapRPCCache_LookupClient interface {
+	Send(*GetRequest) error
+	Recv() (*GetResponse, error)
+	CloseSend() error
+}
+
+type mmapRPCCacheLookupClient struct {
+	stream *client.ClientStream
+}
+
+func (x *mmapRPCCacheLookupClient) Send(m *GetRequest) error {
+	return x.stream.Send(m)
+}
+
+func (x *mmapRPCCacheLookupClient) Recv() (*GetResponse, error) {
+	m := &GetResponse{}
+	if err := x.stream.Recv(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (x *mmapRPCCacheLookupClient) CloseSend() error {
+	return x.stream.CloseSend()
+}
+
 // NewMmapRPCCacheClient creates a new MmapRPCCacheClient
 func NewMmapRPCCacheClient(client *client.Client) MmapRPCCacheClient {
 	return &mmapRPCCacheClient{
@@ -51,32 +158,119 @@ func NewMmapRPCCacheClient(client *client.Client) MmapRPCCacheClient {
 type MmapRPCCacheServer interface {
 	Get(context.Context, *GetRequest) (*GetResponse, error)
 	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Watch(*WatchRequest, MmapRPCCache_WatchServer) error
+	Load(MmapRPCCache_LoadServer) error
+	Lookup(MmapRPCCache_LookupServer) error
 }
 
 // RegisterMmapRPCCacheServer registers the MmapRPCCacheServer with the given server.
 func RegisterMmapRPCCacheServer(s *server.Server, srv MmapRPCCacheServer) {
 	s.RegisterHandler(_Cache_Get_FullMethodName, func(ctx context.Context, data []byte) ([]byte, error) {
-		return handleRequest(ctx, data, srv.Get, &GetRequest{})
+		return server.HandleRequest(ctx, data, srv.Get, &GetRequest{})
 	})
 
 	s.RegisterHandler(_Cache_Set_FullMethodName, func(ctx context.Context, data []byte) ([]byte, error) {
-		return handleRequest(ctx, data, srv.Set, &SetRequest{})
+		return server.HandleRequest(ctx, data, srv.Set, &SetRequest{})
 	})
+
+	s.RegisterStream(&server.StreamDesc{
+		MethodName: _Cache_Watch_FullMethodName,
+		Handler: func(ctx context.Context, data []byte, stream *server.ServerStream) error {
+			in := &WatchRequest{}
+			if err := proto.Unmarshal(data, in); err != nil {
+				return status.Errorf(codes.InvalidArgument, "failed to unmarshal request: %v", err)
+			}
+			return srv.Watch(in, &mmapRPCCacheWatchServer{stream: stream})
+		},
+		ServerStreams: true,
+	})
+
+	s.RegisterStream(&server.StreamDesc{
+		MethodName: _Cache_Load_FullMethodName,
+		Handler: func(ctx context.Context, data []byte, stream *server.ServerStream) error {
+			return srv.Load(&mmapRPCCacheLoadServer{stream: stream})
+		},
+		ClientStreams: true,
+	})
+
+	s.RegisterStream(&server.StreamDesc{
+		MethodName: _Cache_Lookup_FullMethodName,
+		Handler: func(ctx context.Context, data []byte, stream *server.ServerStream) error {
+			return srv.Lookup(&mmapRPCCacheLookupServer{stream: stream})
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	})
+}
+
+// MmapRPCCache_WatchServer is the server API for the Watch stream.
+type MmapRPCCache_WatchServer interface {
+	Send(*WatchEvent) error
+	Context() context.Context
 }
 
-// handleRequest is a helper function to reduce code duplication in RegisterMmapRPCCacheServer
-func handleRequest[Req, Resp proto.Message](
-	ctx context.Context,
-	data []byte,
-	handler func(context.Context, Req) (Resp, error),
-	req Req,
-) ([]byte, error) {
-	if err := proto.Unmarshal(data, req); err != nil {
+type mmapRPCCacheWatchServer struct {
+	stream *server.ServerStream
+}
+
+func (x *mmapRPCCacheWatchServer) Send(m *WatchEvent) error {
+	return x.stream.Send(m)
+}
+
+func (x *mmapRPCCacheWatchServer) Context() context.Context {
+	return x.stream.Context()
+}
+
+// MmapRPCCache_LoadServer is the server API for the Load stream.
+type MmapRPCCache_LoadServer interface {
+	SendAndClose(*LoadResponse) error
+	Recv() (*SetRequest, error)
+	Context() context.Context
+}
+
+type mmapRPCCacheLoadServer struct {
+	stream *server.ServerStream
+}
+
+func (x *mmapRPCCacheLoadServer) SendAndClose(m *LoadResponse) error {
+	return x.stream.Send(m)
+}
+
+func (x *mmapRPCCacheLoadServer) Recv() (*SetRequest, error) {
+	m := &SetRequest{}
+	if err := x.stream.Recv(m); err != nil {
 		return nil, err
 	}
-	resp, err := handler(ctx, req)
-	if err != nil {
+	return m, nil
+}
+
+func (x *mmapRPCCacheLoadServer) Context() context.Context {
+	return x.stream.Context()
+}
+
+// MmapRPCCache_LookupServer is the server API for the Lookup stream.
+type MmapRPCCache_LookupServer interface {
+	Send(*GetResponse) error
+	Recv() (*GetRequest, error)
+	Context() context.Context
+}
+
+type mmapRPCCacheLookupServer struct {
+	stream *server.ServerStream
+}
+
+func (x *mmapRPCCacheLookupServer) Send(m *GetResponse) error {
+	return x.stream.Send(m)
+}
+
+func (x *mmapRPCCacheLookupServer) Recv() (*GetRequest, error) {
+	m := &GetRequest{}
+	if err := x.stream.Recv(m); err != nil {
 		return nil, err
 	}
-	return proto.Marshal(resp)
+	return m, nil
+}
+
+func (x *mmapRPCCacheLookupServer) Context() context.Context {
+	return x.stream.Context()
 }
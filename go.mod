@@ -6,6 +6,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/kyrylo/netstring v1.0.0
 	github.com/tysonmote/gommap v0.0.3
+	golang.org/x/sys v0.35.0
 	google.golang.org/protobuf v1.34.2
 )
 
@@ -0,0 +1,157 @@
+// Package cachetest provides an in-memory implementation of the Cache service and helpers to run
+// it behind a server and connect clients to it in tests.
+package cachetest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/pkg/client"
+	"github.com/epk/mmap-rpc/pkg/server"
+)
+
+// PanicKey makes Get and Watch panic, to test how handlers that panic are reported.
+const PanicKey = "panic"
+
+var _ cache.MmapRPCCacheServer = (*Server)(nil)
+
+// Server is an in-memory implementation of the Cache service. Load sets the values it receives and
+// Lookup gets them, Watch sends the current value of each key.
+type Server struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewServer returns a Server holding no values.
+func NewServer() *Server {
+	return &Server{values: make(map[string]string)}
+}
+
+func (s *Server) Get(ctx context.Context, in *cache.GetRequest) (*cache.GetResponse, error) {
+	if in.Key == PanicKey {
+		panic("Get panicked")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[in.Key]
+	return &cache.GetResponse{Value: value, Found: ok}, nil
+}
+
+func (s *Server) Set(ctx context.Context, in *cache.SetRequest) (*cache.SetResponse, error) {
+	s.SetValue(in.Key, in.Value)
+	return &cache.SetResponse{Success: true}, nil
+}
+
+func (s *Server) Watch(in *cache.WatchRequest, stream cache.MmapRPCCache_WatchServer) error {
+	for _, key := range in.Keys {
+		if key == PanicKey {
+			panic("Watch panicked")
+		}
+		if err := stream.Send(&cache.WatchEvent{Key: key, Value: s.Value(key)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) Load(stream cache.MmapRPCCache_LoadServer) error {
+	var count int64
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&cache.LoadResponse{Count: count})
+		}
+		if err != nil {
+			return err
+		}
+		s.SetValue(in.Key, in.Value)
+		count++
+	}
+}
+
+func (s *Server) Lookup(stream cache.MmapRPCCache_LookupServer) error {
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resp, err := s.Get(stream.Context(), in)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// Value returns the value of key.
+func (s *Server) Value(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.values[key]
+}
+
+// SetValue sets the value of key.
+func (s *Server) SetValue(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+}
+
+// Serve starts srv on a socket in a temporary directory and returns the path of the socket. The
+// server is closed when the test ends.
+func Serve(t testing.TB, srv *server.Server) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "mmap-rpc.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		srv.Close()
+		if err := <-done; !errors.Is(err, server.ErrServerClosed) {
+			t.Errorf("Serve returned %v, want ErrServerClosed", err)
+		}
+	})
+
+	return socketPath
+}
+
+// Dial connects a client to the server listening on socketPath. The client is closed when the
+// test ends.
+func Dial(t testing.TB, socketPath string, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	c, err := client.NewClient(socketPath, opts...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if err := c.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+
+	return c
+}
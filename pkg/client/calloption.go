@@ -0,0 +1,49 @@
+package client
+
+import (
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/metadata"
+)
+
+// CallOption configures a call made with Invoke, NewStream or NewServerStream. The metadata sent
+// to the server is attached to the context of the call, see metadata.NewOutgoingContext.
+type CallOption func(*callInfo)
+
+// callInfo holds the settings of a call.
+type callInfo struct {
+	headers  []*metadata.MD
+	trailers []*metadata.MD
+}
+
+// Header stores the header sent by the server in md once the call completed, also if it failed.
+// For streams it is stored once Recv returned the end of the stream.
+func Header(md *metadata.MD) CallOption {
+	return func(ci *callInfo) {
+		ci.headers = append(ci.headers, md)
+	}
+}
+
+// Trailer stores the trailer sent by the server in md, like Header.
+func Trailer(md *metadata.MD) CallOption {
+	return func(ci *callInfo) {
+		ci.trailers = append(ci.trailers, md)
+	}
+}
+
+func newCallInfo(opts []CallOption) *callInfo {
+	ci := &callInfo{}
+	for _, opt := range opts {
+		opt(ci)
+	}
+	return ci
+}
+
+// received stores the metadata of the response of the call.
+func (ci *callInfo) received(response *api.RPCResponse) {
+	for _, md := range ci.headers {
+		*md = response.Header
+	}
+	for _, md := range ci.trailers {
+		*md = response.Trailer
+	}
+}
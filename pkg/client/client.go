@@ -2,118 +2,626 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
-	"github.com/tysonmote/gommap"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/metadata"
 	"github.com/epk/mmap-rpc/pkg/netstringconn"
+	"github.com/epk/mmap-rpc/pkg/region"
+	"github.com/epk/mmap-rpc/pkg/ring"
+	"github.com/epk/mmap-rpc/pkg/status"
 )
 
+// ErrPayloadTooLarge is returned by Invoke when the request or the response does not fit in the
+// maximum mmap size allowed by the server.
+var ErrPayloadTooLarge = region.ErrPayloadTooLarge
+
+// ErrClosed is returned by Invoke when the client is closed or the connection to the server is lost.
+var ErrClosed = errors.New("client closed")
+
+// ErrServerShutdown is returned by Invoke once the server announced that it is shutting down.
+// Calls already in flight still complete.
+var ErrServerShutdown = errors.New("server shutting down")
+
+// Errors returned by Invoke, wrapped together with the status reported by the server, to tell
+// apart why a call failed.
+var (
+	// ErrConnectionNotFound is returned when the server does not know the client's connection,
+	// for example because it was restarted.
+	ErrConnectionNotFound = errors.New("connection not found")
+	// ErrMethodNotFound is returned when no handler is registered for the method.
+	ErrMethodNotFound = errors.New("method not found")
+	// ErrHandler is returned when the handler of the method returned an error.
+	ErrHandler = errors.New("handler failed")
+)
+
+// defaultResponseCapacity is the number of bytes reserved for the response of each call.
+// Larger responses are fetched with an additional round trip.
+const defaultResponseCapacity = 4096
+
 // Client represents an RPC client using memory-mapped files for data transfer.
+// Invoke may be called from multiple goroutines.
 type Client struct {
 	conn         *netstringconn.NetstringConn
 	connectionID string
-	mmapFile     *os.File
-	mmap         gommap.MMap
+	region       *region.Region
+	mmapSize     int64
+	memfd        bool
+	ring         bool
+	futex        bool
+	interceptors []UnaryClientInterceptor
+	// invoker performs calls through the interceptors.
+	invoker UnaryInvoker
+
+	nextRequestID atomic.Uint64
+	inflight      sync.WaitGroup
+	readerDone    chan struct{}
+
+	// requests and responses are the rings at the start of the region when the server accepted
+	// WithRing, nil otherwise. requestsMu serializes the calls pushing their requests, wake is
+	// signaled when the server queued responses while the consumer of responses was parked, and
+	// ringDone is closed once that consumer stopped.
+	requests   *ring.Ring
+	requestsMu sync.Mutex
+	responses  *ring.Ring
+	wake       chan struct{}
+	ringDone   chan struct{}
+
+	mu        sync.Mutex
+	allocator *region.Allocator
+	// freed is closed and replaced whenever a slot is freed, to wake up callers waiting for space.
+	freed chan struct{}
+	// calls maps request IDs to the callers waiting for their response.
+	calls map[uint64]chan *api.RPCResponse
+	// streams maps request IDs to the streams waiting for their messages.
+	streams map[uint64]*ClientStream
+	closed  bool
+	// goAway is set once the server sent a GoAway message.
+	goAway bool
+	err    error
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithMmapSize requests the initial size of the memory-mapped file. The region still grows
+// on demand when a request or response does not fit.
+func WithMmapSize(size int64) Option {
+	return func(c *Client) {
+		c.mmapSize = size
+	}
+}
+
+// WithMemfd asks the server to create the memory-mapped file with memfd_create and pass it over
+// the socket, so that it never appears in the filesystem. Servers that do not support it fall
+// back to a regular file.
+func WithMemfd() Option {
+	return func(c *Client) {
+		c.memfd = true
+	}
 }
 
 // NewClient creates a new Client instance and establishes a connection to the server.
-func NewClient(socketPath string) (*Client, error) {
+func NewClient(socketPath string, opts ...Option) (*Client, error) {
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 
-	return &Client{
-		conn: netstringconn.NewNetstringConn(conn),
-	}, nil
+	c := &Client{
+		readerDone: make(chan struct{}),
+		freed:      make(chan struct{}),
+		calls:      make(map[uint64]chan *api.RPCResponse),
+		streams:    make(map[uint64]*ClientStream),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if unixConn, ok := conn.(*net.UnixConn); ok && c.memfd {
+		c.conn = netstringconn.NewNetstringConnWithFDs(unixConn)
+	} else {
+		c.conn = netstringconn.NewNetstringConn(conn)
+	}
+	c.invoker = c.chainInterceptors(c.invoke)
+
+	return c, nil
 }
 
 // Connect initializes the connection with the server and sets up the memory-mapped file.
 func (c *Client) Connect() error {
-	connectRequest := &api.ConnectRequest{}
+	connectRequest := &api.ConnectRequest{
+		MmapSize: uint64(c.mmapSize),
+		Memfd:    c.memfd,
+		Ring:     c.ring,
+		Futex:    c.futex,
+	}
 	connectResponse := &api.ConnectResponse{}
 
 	if err := c.sendAndReceive(connectRequest, connectResponse); err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
+	if connectResponse.Error != "" {
+		return fmt.Errorf("failed to connect: %s", connectResponse.Error)
+	}
 
 	c.connectionID = connectResponse.ConnectionId
-	if err := c.setupMmap(connectResponse.MmapFilename); err != nil {
+	if err := c.setupMmap(connectResponse); err != nil {
 		return fmt.Errorf("failed to setup mmap: %w", err)
 	}
+	c.region.SetMaxSize(int64(connectResponse.MaxMmapSize))
+	c.allocator = region.NewAllocator(c.region.Len())
+	c.futex = connectResponse.Ring && connectResponse.Futex
+	if connectResponse.Ring {
+		if err := c.startRings(); err != nil {
+			return fmt.Errorf("failed to setup rings: %w", err)
+		}
+	}
+
+	go c.readResponses()
+
 	return nil
 }
 
-// setupMmap sets up the memory-mapped file for data transfer.
-func (c *Client) setupMmap(filename string) error {
-	file, err := os.OpenFile(filename, os.O_RDWR, 0)
-	if err != nil {
-		return fmt.Errorf("failed to open mmap file: %w", err)
+// setupMmap sets up the memory-mapped file for data transfer, either the file named in the
+// response or the memfd passed along with it.
+func (c *Client) setupMmap(response *api.ConnectResponse) error {
+	var r *region.Region
+	var err error
+	if response.Memfd {
+		fds := c.conn.TakeFDs()
+		if len(fds) != 1 {
+			for _, fd := range fds {
+				syscall.Close(fd)
+			}
+			return fmt.Errorf("expected 1 memfd, received %d", len(fds))
+		}
+		r, err = region.OpenFile(os.NewFile(uintptr(fds[0]), "memfd:"+response.ConnectionId))
+	} else {
+		r, err = region.Open(response.MmapFilename)
 	}
-
-	mmap, err := gommap.Map(file.Fd(), gommap.PROT_READ|gommap.PROT_WRITE, gommap.MAP_SHARED)
 	if err != nil {
-		file.Close()
-		return fmt.Errorf("failed to mmap file: %w", err)
+		return err
 	}
 
-	c.mmap = mmap
-	c.mmapFile = file
+	c.region = r
 
 	return nil
 }
 
-// Close terminates the connection with the server and cleans up resources.
+// ConnectionID returns the ID assigned to the connection by the server, empty before Connect.
+func (c *Client) ConnectionID() string {
+	return c.connectionID
+}
+
+// Close terminates the connection with the server and cleans up resources, the memory-mapped
+// file is unmapped even if the server can no longer be reached. Calls that are still in flight
+// fail with ErrClosed. Calling Close more than once does nothing and returns nil.
 func (c *Client) Close() error {
-	if c.mmapFile != nil {
-		if err := c.mmapFile.Close(); err != nil {
-			return fmt.Errorf("failed to close mmap file: %w", err)
-		}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
 	}
+	c.closed = true
+	c.mu.Unlock()
+
+	var errs []error
 
 	disconnectRequest := &api.DisconnectRequest{
 		ConnectionId: c.connectionID,
 	}
-	if err := c.sendRequest(disconnectRequest); err != nil {
-		return fmt.Errorf("failed to send disconnect request: %w", err)
+	if err := c.sendRequest(context.Background(), disconnectRequest); err != nil {
+		errs = append(errs, fmt.Errorf("failed to send disconnect request: %w", err))
+	}
+
+	if err := c.conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		errs = append(errs, err)
+	}
+
+	if c.region != nil {
+		// Wait for in-flight calls to stop touching the region before unmapping it.
+		<-c.readerDone
+		if c.ringDone != nil {
+			if c.futex {
+				// The consumer of responses may sleep on its ring.
+				c.responses.Interrupt()
+			}
+			<-c.ringDone
+		}
+		c.inflight.Wait()
+
+		if err := c.region.Close(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	return c.conn.Close()
+	return errors.Join(errs...)
 }
 
 // Invoke sends an RPC request to the server and receives the response.
-func (c *Client) Invoke(ctx context.Context, method string, in, out proto.Message) error {
-	inBytes, err := proto.Marshal(in)
+// Errors reported by the server, including the errors returned by handlers, are returned as
+// status errors, see status.FromError. The deadline of ctx is sent to the server, and once ctx is done the server is asked to cancel
+// the call and Invoke returns ctx.Err(). Calls go through the interceptors added with
+// WithUnaryInterceptors. The metadata attached to ctx with metadata.NewOutgoingContext is sent
+// to the server, and opts receive the metadata it sends back.
+func (c *Client) Invoke(ctx context.Context, method string, in, out proto.Message, opts ...CallOption) error {
+	return c.invoker(ctx, method, in, out, opts...)
+}
+
+// invoke performs a call, after the interceptors.
+func (c *Client) invoke(ctx context.Context, method string, in, out proto.Message, opts ...CallOption) error {
+	if err := c.begin(); err != nil {
+		return fmt.Errorf("failed to invoke method %s: %w", method, err)
+	}
+	defer c.inflight.Done()
+
+	size := proto.Size(in)
+
+	// Each call reserves a slot holding the request followed by the area for the response,
+	// so concurrent calls never overlap.
+	requestArea := region.Align(int64(size))
+	responseCapacity := int64(defaultResponseCapacity)
+	if maxSize := c.region.MaxSize(); maxSize > 0 {
+		// Requests close to the maximum size get a smaller response area and fetch larger responses.
+		responseCapacity = max(0, min(responseCapacity, maxSize-requestArea))
+	}
+	area, err := c.allocate(ctx, requestArea+responseCapacity)
 	if err != nil {
-		return fmt.Errorf("failed to marshal input: %w", err)
+		return fmt.Errorf("failed to write request for method %s: %w", method, err)
 	}
+	defer func() {
+		c.free(area)
+	}()
 
-	writeLimit := copy(c.mmap, inBytes)
+	writeLimit, err := c.writeRequest(area, size, in)
+	if err != nil {
+		return err
+	}
 
+	id := c.nextRequestID.Add(1)
 	rpcRequest := &api.RPCRequest{
 		ConnectionId:             c.connectionID,
 		FullyQualifiedMethodName: method,
 		Size:                     uint64(writeLimit),
+		MmapSize:                 uint64(c.region.Len()),
+		Offset:                   uint64(area.offset),
+		RequestId:                id,
+		ResponseOffset:           uint64(area.offset + requestArea),
+		ResponseCapacity:         uint64(area.size - requestArea),
+		Header:                   outgoingHeader(ctx),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		rpcRequest.Timeout = durationpb.New(time.Until(deadline))
 	}
-	rpcResponse := &api.RPCResponse{}
 
-	if err := c.sendAndReceive(rpcRequest, rpcResponse); err != nil {
+	rpcResponse, err := c.roundTrip(ctx, id, rpcRequest, &area)
+	if err != nil {
 		return fmt.Errorf("failed to invoke method %s: %w", method, err)
 	}
+	// Only the first response carries the metadata, not the one answering a FetchRequest.
+	newCallInfo(opts).received(rpcResponse)
+	if err := responseError(rpcResponse); err != nil {
+		return err
+	}
 
-	data := c.mmap[:rpcResponse.Size]
+	if rpcResponse.Pending {
+		// The response did not fit in the slot. The request is no longer needed, so release
+		// the slot before reserving one that is large enough for the response.
+		c.free(area)
+		area = slot{}
+		area, err = c.allocate(ctx, int64(rpcResponse.Size))
+		if err != nil {
+			// Let the server drop the pending response.
+			c.cancel(id)
+			return fmt.Errorf("failed to read response for method %s: %w", method, err)
+		}
+
+		fetchRequest := &api.FetchRequest{
+			ConnectionId: c.connectionID,
+			RequestId:    id,
+			Offset:       uint64(area.offset),
+			MmapSize:     uint64(c.region.Len()),
+		}
+		rpcResponse, err = c.roundTrip(ctx, id, fetchRequest, &area)
+		if err != nil {
+			return fmt.Errorf("failed to invoke method %s: %w", method, err)
+		}
+		if err := responseError(rpcResponse); err != nil {
+			return err
+		}
+	}
+
+	mmap := c.region.Bytes()
+	if rpcResponse.Offset > uint64(len(mmap)) || rpcResponse.Size > uint64(len(mmap))-rpcResponse.Offset {
+		return fmt.Errorf("failed to invoke method %s: response at offset %d with size %d exceeds mmap size %d", method, rpcResponse.Offset, rpcResponse.Size, len(mmap))
+	}
+
+	data := mmap[rpcResponse.Offset : rpcResponse.Offset+rpcResponse.Size]
 	return proto.Unmarshal(data, out)
 }
 
+// outgoingHeader returns the metadata attached to ctx to send to the server.
+func outgoingHeader(ctx context.Context) map[string][]byte {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return md
+}
+
+// writeRequest marshals in straight into the start of area, reusing the size computed by proto.Size.
+func (c *Client) writeRequest(area slot, size int, in proto.Message) (int, error) {
+	buf := c.region.Bytes()[area.offset : area.offset : area.offset+int64(size)]
+	inBytes, err := proto.MarshalOptions{UseCachedSize: true}.MarshalAppend(buf, in)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal input: %w", err)
+	}
+	if len(inBytes) != size {
+		return 0, errors.New("failed to marshal input: message changed while it was marshaled")
+	}
+	return len(inBytes), nil
+}
+
+// responseError returns the status error reported by the server in response, nil on success.
+// Use status.FromError to get the code and details, and errors.Is with ErrConnectionNotFound,
+// ErrMethodNotFound, ErrHandler or ErrPayloadTooLarge to find out where the call failed.
+func responseError(response *api.RPCResponse) error {
+	st := status.FromRPCResponse(response)
+	if st.Code() == codes.OK {
+		return nil
+	}
+
+	var reason error
+	switch response.Reason {
+	case api.ErrorReason_ERROR_REASON_CONNECTION_NOT_FOUND:
+		reason = ErrConnectionNotFound
+	case api.ErrorReason_ERROR_REASON_METHOD_NOT_FOUND:
+		reason = ErrMethodNotFound
+	case api.ErrorReason_ERROR_REASON_HANDLER:
+		reason = ErrHandler
+	case api.ErrorReason_ERROR_REASON_PAYLOAD_TOO_LARGE:
+		reason = ErrPayloadTooLarge
+	default:
+		return st.Err()
+	}
+	return &responseErr{err: st.Err(), reason: reason}
+}
+
+// responseErr is a status error reported by the server that also matches the reason of the failure.
+type responseErr struct {
+	err    error
+	reason error
+}
+
+func (e *responseErr) Error() string {
+	return e.err.Error()
+}
+
+func (e *responseErr) Unwrap() []error {
+	return []error{e.err, e.reason}
+}
+
+// begin registers an in-flight call, it fails once the client is closed.
+func (c *Client) begin() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	if c.goAway {
+		return ErrServerShutdown
+	}
+	if c.err != nil {
+		return c.err
+	}
+	c.inflight.Add(1)
+	return nil
+}
+
+// roundTrip sends a request for the call with the given ID and waits for its response.
+// If ctx is done first, the server is asked to cancel the call and ctx.Err() is returned. The server
+// may still write to the slot of the call until it responds, so roundTrip then takes ownership of
+// the slot, frees it once the response arrives, and clears area.
+func (c *Client) roundTrip(ctx context.Context, id uint64, req proto.Message, area *slot) (*api.RPCResponse, error) {
+	ch := make(chan *api.RPCResponse, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.calls[id] = ch
+	c.mu.Unlock()
+
+	viaRing, err := c.sendCall(ctx, req)
+	if err != nil && !viaRing {
+		c.mu.Lock()
+		delete(c.calls, id)
+		c.mu.Unlock()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	// A request queued in the ring is sent even if waking up the server failed. The server may
+	// still write to the slot, the call completes like the others once the response arrives or
+	// the connection is closed.
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		if viaRing {
+			c.cancelThroughRing(id)
+		} else {
+			c.cancel(id)
+		}
+
+		abandoned := *area
+		*area = slot{}
+		go func() {
+			select {
+			case resp := <-ch:
+				if resp.Pending {
+					// The cancel reached the server too late to drop the response, it holds
+					// it until told that it will not be fetched.
+					c.cancel(id)
+				}
+			case <-c.readerDone:
+			}
+			c.free(abandoned)
+		}()
+
+		return nil, ctx.Err()
+	case <-c.readerDone:
+		// The response may have been delivered just before the reader exited.
+		select {
+		case resp := <-ch:
+			return resp, nil
+		default:
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.calls, id)
+		return nil, c.err
+	}
+}
+
+// cancel asks the server to cancel the call with the given ID. Cancellation is best effort,
+// if it cannot be sent the connection is broken and the reader fails the call anyway.
+func (c *Client) cancel(id uint64) {
+	cancelRequest := &api.CancelRequest{
+		ConnectionId: c.connectionID,
+		RequestId:    id,
+	}
+	_ = c.sendRequest(context.Background(), cancelRequest)
+}
+
+// readResponses reads responses from the server and hands them to the waiting callers
+// until the connection is closed.
+func (c *Client) readResponses() {
+	defer close(c.readerDone)
+
+	for {
+		err := c.readResponse()
+		if err == nil {
+			continue
+		}
+
+		c.mu.Lock()
+		if c.closed {
+			c.err = ErrClosed
+		} else {
+			c.err = fmt.Errorf("%w: %w", ErrClosed, err)
+		}
+		c.mu.Unlock()
+		return
+	}
+}
+
+func (c *Client) readResponse() error {
+	msg, err := c.conn.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	response := &anypb.Any{}
+	if err := proto.Unmarshal(msg, response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	switch response.TypeUrl {
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.RPCResponse{})):
+		typedResponse := &api.RPCResponse{}
+		if err := anypb.UnmarshalTo(response, typedResponse, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal rpc response: %w", err)
+		}
+
+		if err := c.dispatch(typedResponse); err != nil {
+			return err
+		}
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.StreamFrame{})):
+		typedResponse := &api.StreamFrame{}
+		if err := anypb.UnmarshalTo(response, typedResponse, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal stream frame: %w", err)
+		}
+
+		c.mu.Lock()
+		stream := c.streams[typedResponse.RequestId]
+		c.mu.Unlock()
+
+		if stream != nil {
+			stream.push(typedResponse)
+		}
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.StreamAck{})):
+		typedResponse := &api.StreamAck{}
+		if err := anypb.UnmarshalTo(response, typedResponse, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal stream ack: %w", err)
+		}
+
+		c.mu.Lock()
+		stream := c.streams[typedResponse.RequestId]
+		c.mu.Unlock()
+
+		if stream != nil {
+			stream.acked(typedResponse.Messages)
+		}
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.Empty{})):
+		// Acknowledgement of the disconnect request.
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.GoAway{})):
+		c.mu.Lock()
+		c.goAway = true
+		c.mu.Unlock()
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.Wakeup{})):
+		if c.wake != nil {
+			c.wakeup()
+		}
+	default:
+		return fmt.Errorf("unknown response typeUrl: %s", response.TypeUrl)
+	}
+
+	return nil
+}
+
+// dispatch hands response to the caller waiting for it.
+func (c *Client) dispatch(response *api.RPCResponse) error {
+	// The region may have been grown by the server.
+	if err := c.region.Remap(int64(response.MmapSize)); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	ch, ok := c.calls[response.RequestId]
+	delete(c.calls, response.RequestId)
+	stream := c.streams[response.RequestId]
+	c.mu.Unlock()
+
+	if ok {
+		ch <- response
+	} else if stream != nil {
+		// The trailer of a stream.
+		stream.push(response)
+	}
+	return nil
+}
+
 // sendAndReceive sends a request and receives a response.
+// It must not be used once the response reader is running.
 func (c *Client) sendAndReceive(req, resp proto.Message) error {
-	if err := c.sendRequest(req); err != nil {
+	if err := c.sendRequest(context.Background(), req); err != nil {
 		return err
 	}
 
@@ -122,11 +630,16 @@ func (c *Client) sendAndReceive(req, resp proto.Message) error {
 		return fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return proto.Unmarshal(respbuf, resp)
+	any := &anypb.Any{}
+	if err := proto.Unmarshal(respbuf, any); err != nil {
+		return fmt.Errorf("failed to unmarshal any: %w", err)
+	}
+
+	return anypb.UnmarshalTo(any, resp, proto.UnmarshalOptions{})
 }
 
 // sendRequest converts the message to anypb and sends it to the server.
-func (c *Client) sendRequest(msg proto.Message) error {
+func (c *Client) sendRequest(ctx context.Context, msg proto.Message) error {
 	any, err := anypb.New(msg)
 	if err != nil {
 		return fmt.Errorf("failed to create any: %w", err)
@@ -137,5 +650,5 @@ func (c *Client) sendRequest(msg proto.Message) error {
 		return fmt.Errorf("failed to marshal any: %w", err)
 	}
 
-	return c.conn.Write(bytes)
+	return c.conn.WriteContext(ctx, bytes)
 }
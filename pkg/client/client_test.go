@@ -0,0 +1,597 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/internal/cachetest"
+	"github.com/epk/mmap-rpc/pkg/client"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/server"
+	"github.com/epk/mmap-rpc/pkg/status"
+)
+
+// valueOfSize returns a value for which the message returned by newMessage serializes to size bytes.
+func valueOfSize(t *testing.T, size int, newMessage func(value string) proto.Message) string {
+	t.Helper()
+
+	for n := size; n >= 0; n-- {
+		value := strings.Repeat("x", n)
+		if proto.Size(newMessage(value)) == size {
+			return value
+		}
+	}
+	t.Fatalf("no value serializes to %d bytes", size)
+	return ""
+}
+
+func TestPayloadBoundaries(t *testing.T) {
+	const (
+		mmapSize    = 4096
+		maxMmapSize = 4 * mmapSize
+	)
+
+	impl := cachetest.NewServer()
+	srv := &server.Server{MmapSize: mmapSize, MaxMmapSize: maxMmapSize}
+	cache.RegisterMmapRPCCacheServer(srv, impl)
+	cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv)))
+
+	sizes := []struct {
+		size     int
+		tooLarge bool
+	}{
+		{size: 100},
+		{size: mmapSize - 1},
+		{size: mmapSize},
+		{size: mmapSize + 1},
+		{size: maxMmapSize - 1},
+		{size: maxMmapSize},
+		{size: maxMmapSize + 1, tooLarge: true},
+		{size: 2 * maxMmapSize, tooLarge: true},
+	}
+
+	t.Run("request", func(t *testing.T) {
+		for _, tt := range sizes {
+			value := valueOfSize(t, tt.size, func(value string) proto.Message {
+				return &cache.SetRequest{Value: value}
+			})
+			impl.SetValue("", "")
+
+			_, err := cc.Set(context.Background(), &cache.SetRequest{Value: value})
+			if tt.tooLarge {
+				if !errors.Is(err, client.ErrPayloadTooLarge) {
+					t.Errorf("request of %d bytes: got %v, want ErrPayloadTooLarge", tt.size, err)
+				}
+				if impl.Value("") != "" {
+					t.Errorf("request of %d bytes reached the handler", tt.size)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("request of %d bytes: %v", tt.size, err)
+				continue
+			}
+			if got := impl.Value(""); got != value {
+				t.Errorf("request of %d bytes: handler got a value of %d bytes, want %d", tt.size, len(got), len(value))
+			}
+		}
+	})
+
+	t.Run("response", func(t *testing.T) {
+		for _, tt := range sizes {
+			value := valueOfSize(t, tt.size, func(value string) proto.Message {
+				return &cache.GetResponse{Value: value, Found: true}
+			})
+			impl.SetValue("", value)
+
+			resp, err := cc.Get(context.Background(), &cache.GetRequest{})
+			if tt.tooLarge {
+				if !errors.Is(err, client.ErrPayloadTooLarge) {
+					t.Errorf("response of %d bytes: got %v, want ErrPayloadTooLarge", tt.size, err)
+				}
+				if code := status.Code(err); code != codes.ResourceExhausted {
+					t.Errorf("response of %d bytes: got code %v, want ResourceExhausted", tt.size, code)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("response of %d bytes: %v", tt.size, err)
+				continue
+			}
+			if resp.Value != value || !resp.Found {
+				t.Errorf("response of %d bytes: got a value of %d bytes, want %d", tt.size, len(resp.Value), len(value))
+			}
+		}
+	})
+}
+
+// transports are the ways calls can reach the server, to run tests over each of them.
+var transports = []struct {
+	name string
+	opts []client.Option
+}{
+	{name: "socket"},
+	{name: "ring", opts: []client.Option{client.WithRing()}},
+	{name: "futex", opts: []client.Option{client.WithRing(), client.WithFutex()}},
+}
+
+// TestConcurrentCalls hammers a single client from many goroutines, with requests and responses
+// of varying sizes so that calls grow the region and fetch responses concurrently. Run it with
+// -race.
+func TestConcurrentCalls(t *testing.T) {
+	const (
+		goroutines = 32
+		calls      = 100
+	)
+
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			srv := &server.Server{MmapSize: 8192, MaxMmapSize: 1 << 20}
+			cache.RegisterMmapRPCCacheServer(srv, cachetest.NewServer())
+			cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv), transport.opts...))
+
+			var wg sync.WaitGroup
+			for g := range goroutines {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range calls {
+						key := fmt.Sprintf("%d-%d", g, i)
+						value := strings.Repeat(string(rune('a'+g%26)), (g*131+i*17)%9000)
+
+						if _, err := cc.Set(context.Background(), &cache.SetRequest{Key: key, Value: value}); err != nil {
+							t.Errorf("Set(%s): %v", key, err)
+							return
+						}
+						resp, err := cc.Get(context.Background(), &cache.GetRequest{Key: key})
+						if err != nil {
+							t.Errorf("Get(%s): %v", key, err)
+							return
+						}
+						if resp.Value != value {
+							t.Errorf("Get(%s) returned a value of %d bytes, want %d", key, len(resp.Value), len(value))
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// TestCancelPendingResponse cancels a call whose response does not fit in the area reserved for
+// it, while the handler runs. The response must not be held for a fetch that never comes, which
+// would keep Shutdown waiting.
+func TestCancelPendingResponse(t *testing.T) {
+	srv := &server.Server{}
+	srv.RegisterHandler("/test.Test/Slow", func(ctx context.Context, data []byte) ([]byte, error) {
+		<-ctx.Done()
+		return proto.Marshal(&cache.GetResponse{Value: strings.Repeat("x", 64*1024)})
+	})
+	c := cachetest.Dial(t, cachetest.Serve(t, srv))
+
+	// The handler returns once the CancelRequest reached the server.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := c.Invoke(ctx, "/test.Test/Slow", &cache.GetRequest{}, &cache.GetResponse{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+}
+
+// TestCancelRacingCall cancels calls right after sending them. The CancelRequest must not overtake
+// its call, whose handler would then wait for a cancel that already came and keep Shutdown waiting.
+func TestCancelRacingCall(t *testing.T) {
+	const calls = 200
+
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			srv := &server.Server{}
+			srv.RegisterHandler("/test.Test/Slow", func(ctx context.Context, data []byte) ([]byte, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			c := cachetest.Dial(t, cachetest.Serve(t, srv), transport.opts...)
+
+			for i := range calls {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(time.Duration(i%20)*10*time.Microsecond, cancel)
+				err := c.Invoke(ctx, "/test.Test/Slow", &cache.GetRequest{}, &cache.GetResponse{})
+				if status.Code(err) != codes.Canceled && !errors.Is(err, context.Canceled) {
+					t.Fatalf("got %v, want the call canceled", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(ctx); err != nil {
+				t.Errorf("Shutdown: %v", err)
+			}
+		})
+	}
+}
+
+// TestDeadline makes calls whose deadline expires while the handler runs. The call fails with
+// DeadlineExceeded even when the handler ignores its context, and the timeout sent with the call
+// bounds the context of the handler.
+func TestDeadline(t *testing.T) {
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+			calls := make(chan context.Context, 2)
+			srv := &server.Server{}
+			srv.RegisterHandler("/test.Test/Hung", func(ctx context.Context, data []byte) ([]byte, error) {
+				calls <- ctx
+				<-release
+				return nil, ctx.Err()
+			})
+			srv.RegisterHandler("/test.Test/Slow", func(ctx context.Context, data []byte) ([]byte, error) {
+				calls <- ctx
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+			c := cachetest.Dial(t, cachetest.Serve(t, srv), transport.opts...)
+
+			for _, method := range []string{"/test.Test/Hung", "/test.Test/Slow"} {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				deadline, _ := ctx.Deadline()
+
+				err := c.Invoke(ctx, method, &cache.GetRequest{}, &cache.GetResponse{})
+				if !errors.Is(err, context.DeadlineExceeded) && status.Code(err) != codes.DeadlineExceeded {
+					t.Errorf("%s: got %v, want DeadlineExceeded", method, err)
+				}
+
+				// The server starts the timeout when it receives the call, a little after the client.
+				callCtx := <-calls
+				if d, ok := callCtx.Deadline(); !ok || d.After(deadline.Add(time.Second)) {
+					t.Errorf("%s: got handler deadline %v, want about %v", method, d, deadline)
+				}
+				select {
+				case <-callCtx.Done():
+				case <-time.After(5 * time.Second):
+					t.Errorf("%s: the context of the handler outlived the deadline", method)
+				}
+			}
+		})
+	}
+}
+
+// TestExpiredContext makes a call whose context expired before it was sent, while the server waits
+// for requests. The next calls must still reach the server.
+func TestExpiredContext(t *testing.T) {
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			srv := &server.Server{}
+			cache.RegisterMmapRPCCacheServer(srv, cachetest.NewServer())
+			cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv), transport.opts...))
+
+			for range 10 {
+				// Let the consumer of the request ring park.
+				time.Sleep(20 * time.Millisecond)
+
+				// The call may still complete if the response comes before the deadline is noticed.
+				ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+				_, err := cc.Get(ctx, &cache.GetRequest{Key: "key"})
+				cancel()
+				if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("got %v, want context.DeadlineExceeded", err)
+				}
+
+				ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+				_, err = cc.Get(ctx, &cache.GetRequest{Key: "key"})
+				cancel()
+				if err != nil {
+					t.Fatalf("Get after an expired call: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestErrors checks how each way a call can fail on the server is reported to the caller.
+func TestErrors(t *testing.T) {
+	srv := &server.Server{MaxMmapSize: 1 << 20}
+	cache.RegisterMmapRPCCacheServer(srv, cachetest.NewServer())
+	srv.RegisterHandler("/test.Test/Plain", func(ctx context.Context, data []byte) ([]byte, error) {
+		return nil, errors.New("plain error")
+	})
+	srv.RegisterHandler("/test.Test/Status", func(ctx context.Context, data []byte) ([]byte, error) {
+		st, err := status.New(codes.NotFound, "no such key").WithDetails(&cache.GetRequest{Key: "key"})
+		if err != nil {
+			return nil, err
+		}
+		return nil, st.Err()
+	})
+	srv.RegisterHandler("/test.Test/Large", func(ctx context.Context, data []byte) ([]byte, error) {
+		return make([]byte, 2<<20), nil
+	})
+	socketPath := cachetest.Serve(t, srv)
+	c := cachetest.Dial(t, socketPath)
+
+	tests := []struct {
+		name    string
+		c       *client.Client
+		method  string
+		in      proto.Message
+		code    codes.Code
+		reason  error
+		details []proto.Message
+	}{
+		{
+			name:   "unknown method",
+			method: "/test.Test/Unknown",
+			in:     &cache.GetRequest{},
+			code:   codes.Unimplemented,
+			reason: client.ErrMethodNotFound,
+		},
+		{
+			name:   "streaming method",
+			method: "/cache.Cache/Watch",
+			in:     &cache.WatchRequest{},
+			code:   codes.Unimplemented,
+		},
+		{
+			name:   "plain handler error",
+			method: "/test.Test/Plain",
+			in:     &cache.GetRequest{},
+			code:   codes.Unknown,
+			reason: client.ErrHandler,
+		},
+		{
+			name:    "status handler error",
+			method:  "/test.Test/Status",
+			in:      &cache.GetRequest{},
+			code:    codes.NotFound,
+			reason:  client.ErrHandler,
+			details: []proto.Message{&cache.GetRequest{Key: "key"}},
+		},
+		{
+			// Field 1 of GetRequest is a string, which must be valid UTF-8.
+			name:   "malformed request",
+			method: "/cache.Cache/Get",
+			in:     wrapperspb.Bytes([]byte{0xff}),
+			code:   codes.InvalidArgument,
+			reason: client.ErrHandler,
+		},
+		{
+			name:    "response too large",
+			method:  "/test.Test/Large",
+			in:      &cache.GetRequest{},
+			code:    codes.ResourceExhausted,
+			reason:  client.ErrPayloadTooLarge,
+			details: []proto.Message{&api.PayloadTooLarge{Size: 2 << 20, MaxMmapSize: 1 << 20}},
+		},
+		{
+			name: "unknown connection",
+			c: func() *client.Client {
+				c := cachetest.Dial(t, socketPath)
+				client.SetConnectionID(c, "unknown")
+				return c
+			}(),
+			method: "/cache.Cache/Get",
+			in:     &cache.GetRequest{},
+			code:   codes.FailedPrecondition,
+			reason: client.ErrConnectionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.c == nil {
+				tt.c = c
+			}
+
+			err := tt.c.Invoke(context.Background(), tt.method, tt.in, &cache.GetResponse{})
+			st, ok := status.FromError(err)
+			if !ok {
+				t.Fatalf("got %v, want a status error", err)
+			}
+			if st.Code() != tt.code {
+				t.Errorf("got code %v, want %v", st.Code(), tt.code)
+			}
+			for _, reason := range []error{client.ErrConnectionNotFound, client.ErrMethodNotFound, client.ErrHandler, client.ErrPayloadTooLarge} {
+				if got, want := errors.Is(err, reason), reason == tt.reason; got != want {
+					t.Errorf("errors.Is(%v, %v) = %t, want %t", err, reason, got, want)
+				}
+			}
+			if details := st.Details(); len(details) != len(tt.details) {
+				t.Errorf("got details %v, want %v", details, tt.details)
+			} else {
+				for i := range details {
+					if !proto.Equal(details[i].(proto.Message), tt.details[i]) {
+						t.Errorf("got details %v, want %v", details, tt.details)
+					}
+				}
+			}
+		})
+	}
+
+	// The connection is still usable after the failed calls.
+	if _, err := cache.NewMmapRPCCacheClient(c).Set(context.Background(), &cache.SetRequest{Key: "key"}); err != nil {
+		t.Errorf("Set after the failed calls: %v", err)
+	}
+}
+
+// mappings returns the number of mappings of the process that are mmap files in dir or memfds.
+func mappings(t *testing.T, dir string) int {
+	t.Helper()
+
+	maps, err := os.ReadFile("/proc/self/maps")
+	if err != nil {
+		t.Fatalf("failed to read mappings: %v", err)
+	}
+	n := 0
+	for _, line := range strings.Split(string(maps), "\n") {
+		if strings.Contains(line, dir) || strings.Contains(line, "/memfd:") {
+			n++
+		}
+	}
+	return n
+}
+
+// TestConnectError checks that Connect returns the error of a server that failed to set up the
+// connection, rather than a failure to open a file it never created.
+func TestConnectError(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := os.Mkdir(missing, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	srv := &server.Server{MmapFilePrefix: missing + string(filepath.Separator)}
+	socketPath := cachetest.Serve(t, srv)
+	// The server checks the directory when it starts, connecting once makes sure it did. It fails
+	// to create files once the directory is gone.
+	cachetest.Dial(t, socketPath)
+	if err := os.RemoveAll(missing); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := client.NewClient(socketPath)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	err = c.Connect()
+	if err == nil || !strings.Contains(err.Error(), missing) {
+		t.Fatalf("got %v, want the error of the server about %s", err, missing)
+	}
+	if strings.Contains(err.Error(), "failed to setup mmap") {
+		t.Errorf("got %v, want the error of the server", err)
+	}
+}
+
+// eventually fails the test if cond does not hold within a few seconds.
+func eventually(t *testing.T, cond func() bool, format string, a ...any) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, a...)
+		}
+	}
+}
+
+// TestCloseReleasesMappings opens and closes many connections, with responses that grow the
+// region, and checks that neither side leaves mappings or mmap files behind.
+func TestCloseReleasesMappings(t *testing.T) {
+	if _, err := os.Stat("/proc/self/maps"); err != nil {
+		t.Skip("/proc/self/maps is not available")
+	}
+
+	dir := t.TempDir()
+	impl := cachetest.NewServer()
+	impl.SetValue("key", strings.Repeat("x", 20000))
+	srv := &server.Server{MmapSize: 4096, MmapFilePrefix: dir + string(filepath.Separator)}
+	cache.RegisterMmapRPCCacheServer(srv, impl)
+	socketPath := cachetest.Serve(t, srv)
+
+	before := mappings(t, dir)
+	for i := range 2000 {
+		var opts []client.Option
+		if i%2 == 1 {
+			opts = append(opts, client.WithMemfd())
+		}
+		c, err := client.NewClient(socketPath, opts...)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		if err := c.Connect(); err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		if _, err := cache.NewMmapRPCCacheClient(c).Get(context.Background(), &cache.GetRequest{Key: "key"}); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatalf("second Close: %v", err)
+		}
+	}
+
+	// The server releases the connections once it handled their disconnect requests.
+	eventually(t, func() bool {
+		return mappings(t, dir) == before
+	}, "mappings left after closing the clients")
+	eventually(t, func() bool {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.mmap"))
+		return len(files) == 0
+	}, "mmap files left after closing the clients")
+}
+
+// BenchmarkCall measures the latency of Get calls of 64-byte values over each transport, one at a
+// time.
+func BenchmarkCall(b *testing.B) {
+	for _, transport := range transports {
+		b.Run(transport.name, func(b *testing.B) {
+			cc := benchmarkClient(b, transport.opts)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := cc.Get(context.Background(), &cache.GetRequest{Key: "key"}); err != nil {
+					b.Fatalf("Get: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCallParallel is like BenchmarkCall with concurrent callers sharing the client, run it
+// with -cpu to vary their number.
+func BenchmarkCallParallel(b *testing.B) {
+	for _, transport := range transports {
+		b.Run(transport.name, func(b *testing.B) {
+			cc := benchmarkClient(b, transport.opts)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := cc.Get(context.Background(), &cache.GetRequest{Key: "key"}); err != nil {
+						b.Errorf("Get: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// benchmarkClient starts a cache server holding a 64-byte value for "key" and returns a client
+// connected to it with opts, warmed up by a first call.
+func benchmarkClient(b *testing.B, opts []client.Option) cache.MmapRPCCacheClient {
+	b.Helper()
+
+	cs := cachetest.NewServer()
+	cs.SetValue("key", strings.Repeat("x", 64))
+	srv := &server.Server{}
+	cache.RegisterMmapRPCCacheServer(srv, cs)
+	cc := cache.NewMmapRPCCacheClient(cachetest.Dial(b, cachetest.Serve(b, srv), opts...))
+
+	if _, err := cc.Get(context.Background(), &cache.GetRequest{Key: "key"}); err != nil {
+		b.Fatalf("Get: %v", err)
+	}
+	return cc
+}
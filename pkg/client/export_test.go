@@ -0,0 +1,7 @@
+package client
+
+// SetConnectionID replaces the connection ID sent with the calls of c, to test how the server
+// handles calls for connections it does not know.
+func SetConnectionID(c *Client, id string) {
+	c.connectionID = id
+}
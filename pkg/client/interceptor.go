@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// UnaryInvoker performs a call, it is the innermost step of the interceptor chain.
+type UnaryInvoker func(ctx context.Context, method string, in, out proto.Message, opts ...CallOption) error
+
+// UnaryClientInterceptor intercepts calls made with Invoke. The interceptor calls invoker to
+// perform the call or returns early to fail it. c is the client making the call, its
+// ConnectionID identifies the connection. opts are the options of the call, to pass on to invoker.
+type UnaryClientInterceptor func(ctx context.Context, method string, in, out proto.Message, c *Client, invoker UnaryInvoker, opts ...CallOption) error
+
+// WithUnaryInterceptors adds interceptors that are run around every call, in order, the first
+// one being the outermost.
+func WithUnaryInterceptors(interceptors ...UnaryClientInterceptor) Option {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// chainInterceptors wraps invoker with the interceptors of the client.
+func (c *Client) chainInterceptors(invoker UnaryInvoker) UnaryInvoker {
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.interceptors[i], invoker
+		invoker = func(ctx context.Context, method string, in, out proto.Message, opts ...CallOption) error {
+			return interceptor(ctx, method, in, out, c, next, opts...)
+		}
+	}
+	return invoker
+}
@@ -0,0 +1,75 @@
+package client_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/internal/cachetest"
+	"github.com/epk/mmap-rpc/pkg/client"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/server"
+	"github.com/epk/mmap-rpc/pkg/status"
+)
+
+// TestUnaryInterceptors checks that the first interceptor is the outermost, that interceptors see
+// the method and the client of the call, and that one can fail a call before it is sent.
+func TestUnaryInterceptors(t *testing.T) {
+	var (
+		steps   []string
+		methods []string
+		clients []*client.Client
+	)
+	interceptor := func(name string) client.UnaryClientInterceptor {
+		return func(ctx context.Context, method string, in, out proto.Message, c *client.Client, invoker client.UnaryInvoker, opts ...client.CallOption) error {
+			steps = append(steps, name+" before")
+			methods = append(methods, method)
+			clients = append(clients, c)
+			err := invoker(ctx, method, in, out, opts...)
+			steps = append(steps, name+" after")
+			return err
+		}
+	}
+	// reject fails the calls setting the key "secret" without sending them.
+	reject := func(ctx context.Context, method string, in, out proto.Message, c *client.Client, invoker client.UnaryInvoker, opts ...client.CallOption) error {
+		if req, ok := in.(*cache.SetRequest); ok && req.Key == "secret" {
+			return status.Error(codes.PermissionDenied, "access denied")
+		}
+		steps = append(steps, "invoker")
+		return invoker(ctx, method, in, out, opts...)
+	}
+
+	srv := &server.Server{}
+	impl := cachetest.NewServer()
+	cache.RegisterMmapRPCCacheServer(srv, impl)
+	c := cachetest.Dial(t, cachetest.Serve(t, srv), client.WithUnaryInterceptors(interceptor("first"), interceptor("second")), client.WithUnaryInterceptors(reject))
+	cc := cache.NewMmapRPCCacheClient(c)
+
+	if _, err := cc.Set(context.Background(), &cache.SetRequest{Key: "key", Value: "value"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	want := []string{"first before", "second before", "invoker", "second after", "first after"}
+	if !slices.Equal(steps, want) {
+		t.Errorf("got steps %q, want %q", steps, want)
+	}
+	for i := range methods {
+		if methods[i] != "/cache.Cache/Set" || clients[i] != c {
+			t.Errorf("interceptor got method %q and client %p, want %q and %p", methods[i], clients[i], "/cache.Cache/Set", c)
+		}
+	}
+	if c.ConnectionID() == "" {
+		t.Error("the client of the interceptors has no connection ID")
+	}
+
+	steps = nil
+	_, err := cc.Set(context.Background(), &cache.SetRequest{Key: "secret", Value: "value"})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("got %v, want code PermissionDenied", err)
+	}
+	if slices.Contains(steps, "invoker") || impl.Value("secret") != "" {
+		t.Error("the rejected call reached the server")
+	}
+}
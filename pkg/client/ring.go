@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/ring"
+)
+
+// ringArea is the part of the region taken by the rings, the request ring followed by the
+// response ring.
+const ringArea = 2 * ring.Size
+
+// WithRing asks the server to exchange RPC requests and responses through rings in the
+// memory-mapped file instead of the socket, which is then only used to wake up the other side
+// when it waits for messages. Calls fall back to the socket when a ring is full, and servers that
+// do not support rings keep using the socket.
+func WithRing() Option {
+	return func(c *Client) {
+		c.ring = true
+	}
+}
+
+// WithFutex is like WithRing, but parked consumers of the rings sleep on a futex in the
+// memory-mapped file and are woken up with FUTEX_WAKE instead of Wakeup messages on the socket, so
+// calls do not involve the socket at all (Linux only). Before sleeping, consumers poll the ring for
+// a while that adapts to how fast messages arrive. Servers that do not support futexes fall back
+// to Wakeup messages.
+func WithFutex() Option {
+	return func(c *Client) {
+		c.ring = true
+		c.futex = ring.FutexSupported
+	}
+}
+
+// startRings sets up the rings at the start of the region and starts consuming responses.
+func (c *Client) startRings() error {
+	// The rings are never handed out to calls. The allocator is empty, so they get offset 0.
+	area, ok := c.allocator.Alloc(ringArea)
+	if !ok || area != 0 {
+		return fmt.Errorf("mmap size %d is too small for the rings", c.region.Len())
+	}
+
+	mmap := c.region.Bytes()
+	c.requests = ring.NewProducer(mmap[:ring.Size])
+	c.responses = ring.NewConsumer(mmap[ring.Size:ringArea])
+	c.wake = make(chan struct{}, 1)
+	c.ringDone = make(chan struct{})
+
+	go c.consumeResponses()
+	return nil
+}
+
+// consumeResponses hands the responses queued by the server in the response ring to the waiting
+// callers until the connection is closed. A corrupted ring closes the connection.
+func (c *Client) consumeResponses() {
+	defer close(c.ringDone)
+
+	for {
+		msg, err := c.responses.Pop()
+		if err == nil && msg == nil {
+			if !c.responses.Spin() && c.responses.Park() && !c.waitForResponses() {
+				return
+			}
+			continue
+		}
+
+		response := &api.RPCResponse{}
+		if err == nil {
+			err = proto.Unmarshal(msg, response)
+		}
+		if err == nil {
+			err = c.dispatch(response)
+		}
+		if err != nil {
+			// The reader fails the calls in flight once the connection is closed.
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// waitForResponses blocks the parked consumer of responses until it is woken up. It returns false
+// if the connection was closed instead.
+func (c *Client) waitForResponses() bool {
+	if c.futex {
+		// Close interrupts the sleep once the connection is closed. If the server goes away,
+		// the sleep times out and the consumer notices on the next attempt.
+		select {
+		case <-c.readerDone:
+			return false
+		default:
+		}
+		c.responses.Sleep()
+		return true
+	}
+
+	select {
+	case <-c.wake:
+		return true
+	case <-c.readerDone:
+		return false
+	}
+}
+
+// wakeup resumes the consumer of responses if it is parked.
+func (c *Client) wakeup() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sendCall sends req for a call, through the request ring if there is one and it has room for
+// the request, and otherwise over the socket. It reports whether req went through the ring.
+func (c *Client) sendCall(ctx context.Context, req proto.Message) (bool, error) {
+	rpcRequest, ok := req.(*api.RPCRequest)
+	if !ok || c.requests == nil {
+		return false, c.sendRequest(ctx, req)
+	}
+
+	pushed, err := c.pushRequest(rpcRequest)
+	if pushed || err != nil {
+		return pushed, err
+	}
+	return false, c.sendRequest(ctx, req)
+}
+
+// pushRequest queues msg in the request ring and wakes up the server if it is parked. It reports
+// false if the ring has no room for msg. An error after msg was queued means that the connection
+// is broken.
+func (c *Client) pushRequest(msg proto.Message) (bool, error) {
+	any, err := anypb.New(msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to create any: %w", err)
+	}
+	data, err := proto.Marshal(any)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal any: %w", err)
+	}
+
+	c.requestsMu.Lock()
+	pushed, wake := c.requests.Push(data)
+	c.requestsMu.Unlock()
+
+	if !pushed {
+		return false, nil
+	}
+	if wake && c.futex {
+		c.requests.Wake()
+		return true, nil
+	}
+	if wake {
+		// Push cleared the waiting flag of the server, so later pushes do not wake it up. The
+		// Wakeup must be sent even if the context of the call is done.
+		return true, c.sendRequest(context.Background(), &api.Wakeup{ConnectionId: c.connectionID})
+	}
+	return true, nil
+}
+
+// cancelThroughRing is like cancel for a call sent through the request ring. The CancelRequest
+// follows the call through the ring, so that the server starts the call before it sees the cancel,
+// and waits for room in the ring if needed.
+func (c *Client) cancelThroughRing(id uint64) {
+	cancelRequest := &api.CancelRequest{
+		ConnectionId: c.connectionID,
+		RequestId:    id,
+	}
+	for {
+		pushed, err := c.pushRequest(cancelRequest)
+		if pushed || err != nil {
+			return
+		}
+		// The server consumes the ring until the connection is closed.
+		select {
+		case <-c.readerDone:
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
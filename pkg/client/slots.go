@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/epk/mmap-rpc/pkg/region"
+)
+
+// slot is an area of the region reserved for a single call.
+type slot struct {
+	offset int64
+	size   int64
+}
+
+// allocate reserves a slot of at least size bytes. The region is grown when it has no room left,
+// and once it reached its maximum size allocate waits for other calls to free their slots.
+func (c *Client) allocate(ctx context.Context, size int64) (slot, error) {
+	size = region.Align(size)
+	if size == 0 {
+		// Empty messages take no room, the region may have none left.
+		return slot{}, nil
+	}
+	if maxSize := c.region.MaxSize(); maxSize > 0 && size > maxSize {
+		return slot{}, fmt.Errorf("%w: %d bytes exceeds maximum mmap size %d", ErrPayloadTooLarge, size, maxSize)
+	}
+
+	for {
+		c.mu.Lock()
+		if offset, ok := c.allocator.Alloc(size); ok {
+			c.mu.Unlock()
+			return slot{offset: offset, size: size}, nil
+		}
+
+		err := c.region.Grow(c.allocator.Size() + size)
+		if err == nil {
+			c.allocator.Extend(c.region.Len())
+			c.mu.Unlock()
+			continue
+		}
+		if !errors.Is(err, ErrPayloadTooLarge) {
+			c.mu.Unlock()
+			return slot{}, err
+		}
+		if c.allocator.Size() < c.region.MaxSize() {
+			// Grow as far as allowed, the free space at the end may then be large enough.
+			if err := c.region.Grow(c.region.MaxSize()); err != nil {
+				c.mu.Unlock()
+				return slot{}, err
+			}
+			c.allocator.Extend(c.region.Len())
+			c.mu.Unlock()
+			continue
+		}
+
+		freed := c.freed
+		c.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-c.readerDone:
+			return slot{}, ErrClosed
+		case <-ctx.Done():
+			return slot{}, ctx.Err()
+		}
+	}
+}
+
+// free releases a slot returned by allocate and wakes up callers waiting for space.
+func (c *Client) free(s slot) {
+	if s.size == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.allocator.Free(s.offset, s.size)
+	close(c.freed)
+	c.freed = make(chan struct{})
+}
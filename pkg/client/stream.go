@@ -0,0 +1,517 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/region"
+	"github.com/epk/mmap-rpc/pkg/status"
+)
+
+// defaultStreamWindow is the number of bytes reserved for the messages of a stream that were not
+// read yet. Larger messages make the client reserve a larger window.
+const defaultStreamWindow = 64 * 1024
+
+// StreamDesc describes a streaming method.
+type StreamDesc struct {
+	// ServerStreams and ClientStreams report whether the server and the client send a stream of
+	// messages.
+	ServerStreams bool
+	ClientStreams bool
+}
+
+// ClientStream is the client side of a streaming call, see NewStream and NewServerStream.
+// Recv must not be called concurrently, and neither must Send and CloseSend, but one goroutine
+// may send while another one receives.
+type ClientStream struct {
+	c   *Client
+	ctx context.Context
+	id  uint64
+	// serverStreams is set if the server sends a stream of messages, rather than a single one.
+	serverStreams bool
+	// info receives the metadata of the trailer.
+	info *callInfo
+	// sendCtx is canceled once the stream ended, to stop Send from waiting for space.
+	sendCtx    context.Context
+	cancelSend context.CancelFunc
+	// area is the slot holding the request followed by the initial window, window is the slot
+	// of the larger window that replaced it, if any. The request stays in place until the
+	// stream ends, the handler may still read it.
+	area   slot
+	window slot
+
+	// notify is signaled whenever a message is queued.
+	notify chan struct{}
+
+	// mu guards the fields below.
+	mu sync.Mutex
+	// queue holds the *api.StreamFrame messages received and not read yet, followed by the
+	// *api.RPCResponse that ends the stream.
+	queue []proto.Message
+	// err is returned by Recv once the stream ended, io.EOF if it ended successfully.
+	err error
+	// abandoned is set if the stream failed before the server ended it, the slots are released
+	// once it does.
+	abandoned bool
+	// sent holds the slots of the messages sent and not acknowledged by the server yet, in the
+	// order they were sent, and sendClosed is set once the client sent its last message.
+	sent       []slot
+	sendClosed bool
+	// ended is set once the trailer arrived, the server no longer reads the messages sent.
+	ended bool
+	// released is set once the slots of the stream were freed.
+	released bool
+}
+
+// NewServerStream opens a server-streaming call of method with the request in, the messages are
+// then received with Recv. The stream ends when the server returns from the handler, and once
+// ctx is done the server is asked to cancel it. Unary interceptors are not run for streams.
+func (c *Client) NewServerStream(ctx context.Context, method string, in proto.Message, opts ...CallOption) (*ClientStream, error) {
+	return c.openStream(ctx, &StreamDesc{ServerStreams: true}, method, in, opts)
+}
+
+// NewStream opens a call of method where the client streams, the messages are sent with Send and
+// CloseSend, and received with Recv. If the server does not stream, Recv returns its single
+// message once the call succeeded. The stream ends like the ones of NewServerStream.
+func (c *Client) NewStream(ctx context.Context, desc *StreamDesc, method string, opts ...CallOption) (*ClientStream, error) {
+	if !desc.ClientStreams {
+		return nil, fmt.Errorf("failed to open stream %s: the client does not stream, use NewServerStream", method)
+	}
+	return c.openStream(ctx, desc, method, nil, opts)
+}
+
+// openStream opens a stream with the request in, or with an empty request if in is nil.
+func (c *Client) openStream(ctx context.Context, desc *StreamDesc, method string, in proto.Message, opts []CallOption) (*ClientStream, error) {
+	if err := c.begin(); err != nil {
+		return nil, fmt.Errorf("failed to open stream %s: %w", method, err)
+	}
+	defer c.inflight.Done()
+
+	var size int
+	if in != nil {
+		size = proto.Size(in)
+	}
+	requestArea := region.Align(int64(size))
+	window := int64(defaultStreamWindow)
+	if maxSize := c.region.MaxSize(); maxSize > 0 {
+		window = max(0, min(window, maxSize-requestArea))
+	}
+	area, err := c.allocate(ctx, requestArea+window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write request for stream %s: %w", method, err)
+	}
+
+	var writeLimit int
+	if in != nil {
+		if writeLimit, err = c.writeRequest(area, size, in); err != nil {
+			c.free(area)
+			return nil, err
+		}
+	}
+
+	sendCtx, cancelSend := context.WithCancel(ctx)
+	stream := &ClientStream{
+		c:             c,
+		ctx:           ctx,
+		id:            c.nextRequestID.Add(1),
+		serverStreams: desc.ServerStreams,
+		info:          newCallInfo(opts),
+		sendCtx:       sendCtx,
+		cancelSend:    cancelSend,
+		area:          area,
+		notify:        make(chan struct{}, 1),
+		sendClosed:    !desc.ClientStreams,
+	}
+	rpcRequest := &api.RPCRequest{
+		ConnectionId:             c.connectionID,
+		FullyQualifiedMethodName: method,
+		Size:                     uint64(writeLimit),
+		MmapSize:                 uint64(c.region.Len()),
+		Offset:                   uint64(area.offset),
+		RequestId:                stream.id,
+		ResponseOffset:           uint64(area.offset + requestArea),
+		ResponseCapacity:         uint64(area.size - requestArea),
+		Stream:                   true,
+		Header:                   outgoingHeader(ctx),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		rpcRequest.Timeout = durationpb.New(time.Until(deadline))
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		cancelSend()
+		c.free(area)
+		return nil, err
+	}
+	c.streams[stream.id] = stream
+	c.mu.Unlock()
+
+	// The request goes over the socket, so that the frames sent next cannot overtake it.
+	if err := c.sendRequest(ctx, rpcRequest); err != nil {
+		stream.release()
+		return nil, fmt.Errorf("failed to open stream %s: %w", method, err)
+	}
+
+	return stream, nil
+}
+
+// Send writes m to a slot of the memory-mapped file and sends it to the server, which frees the
+// slot once it read the message. Send blocks while the region has no room for m. It returns io.EOF
+// once the stream ended, Recv then returns its status.
+func (cs *ClientStream) Send(m proto.Message) error {
+	c := cs.c
+	if err := c.enter(); err != nil {
+		return err
+	}
+	defer c.inflight.Done()
+
+	cs.mu.Lock()
+	sendClosed, ended := cs.sendClosed, cs.ended || cs.err != nil
+	cs.mu.Unlock()
+	if ended {
+		return io.EOF
+	}
+	if sendClosed {
+		return errors.New("failed to send stream message: send side is closed")
+	}
+
+	size := proto.Size(m)
+	area, err := c.allocate(cs.sendCtx, int64(size))
+	if err != nil {
+		if cs.sendCtx.Err() != nil && cs.ctx.Err() == nil {
+			return io.EOF
+		}
+		return fmt.Errorf("failed to send stream message: %w", err)
+	}
+	if _, err := c.writeRequest(area, size, m); err != nil {
+		c.free(area)
+		return err
+	}
+
+	cs.mu.Lock()
+	if cs.ended || cs.released {
+		cs.mu.Unlock()
+		c.free(area)
+		return io.EOF
+	}
+	cs.sent = append(cs.sent, area)
+	cs.mu.Unlock()
+
+	// The slot is freed along with the stream if the frame is not sent.
+	return c.sendRequest(cs.ctx, &api.StreamFrame{
+		ConnectionId: c.connectionID,
+		RequestId:    cs.id,
+		Offset:       uint64(area.offset),
+		Size:         uint64(size),
+		MmapSize:     uint64(c.region.Len()),
+	})
+}
+
+// CloseSend tells the server that the client sent its last message. It does nothing if the stream
+// ended or the send side is already closed.
+func (cs *ClientStream) CloseSend() error {
+	c := cs.c
+	if err := c.enter(); err != nil {
+		return err
+	}
+	defer c.inflight.Done()
+
+	cs.mu.Lock()
+	if cs.sendClosed || cs.ended || cs.err != nil {
+		cs.mu.Unlock()
+		return nil
+	}
+	cs.sendClosed = true
+	cs.mu.Unlock()
+
+	return c.sendRequest(cs.ctx, &api.StreamFrame{
+		ConnectionId: c.connectionID,
+		RequestId:    cs.id,
+		CloseSend:    true,
+	})
+}
+
+// Recv reads the next message of the stream into m. It returns io.EOF once the stream ended
+// successfully, and otherwise the status error of the trailer, like Invoke. Once the context of
+// the stream is done, Recv returns ctx.Err().
+func (cs *ClientStream) Recv(m proto.Message) error {
+	if err := cs.c.enter(); err != nil {
+		return err
+	}
+	defer cs.c.inflight.Done()
+
+	for {
+		msg, err := cs.next()
+		if err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *api.StreamFrame:
+			if msg.Pending {
+				if err := cs.growWindow(int64(msg.Size)); err != nil {
+					cs.abandon(err)
+					return err
+				}
+				continue
+			}
+			if err := cs.read(msg, m); err != nil || cs.serverStreams {
+				return err
+			}
+			// The single message of the server is only valid once the call succeeded.
+			return cs.recvTrailer()
+		case *api.RPCResponse:
+			err := cs.end(msg)
+			if err == io.EOF && !cs.serverStreams {
+				return status.Error(codes.Internal, "the server ended the stream without a message")
+			}
+			return err
+		}
+	}
+}
+
+// recvTrailer waits for the trailer once the single message of the server was read.
+func (cs *ClientStream) recvTrailer() error {
+	for {
+		msg, err := cs.next()
+		if err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *api.StreamFrame:
+			err := status.Error(codes.Internal, "the server sent more than one message")
+			cs.abandon(err)
+			return err
+		case *api.RPCResponse:
+			if err := cs.end(msg); err != io.EOF {
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// end ends the stream with the status of the trailer and returns it, io.EOF if the stream ended
+// successfully.
+func (cs *ClientStream) end(trailer *api.RPCResponse) error {
+	cs.info.received(trailer)
+	err := responseError(trailer)
+	if err == nil {
+		err = io.EOF
+	}
+	cs.mu.Lock()
+	cs.err = err
+	cs.mu.Unlock()
+	cs.release()
+	return err
+}
+
+// next returns the next message queued for the stream.
+func (cs *ClientStream) next() (proto.Message, error) {
+	for {
+		cs.mu.Lock()
+		if cs.err != nil {
+			err := cs.err
+			cs.mu.Unlock()
+			return nil, err
+		}
+		if len(cs.queue) > 0 {
+			msg := cs.queue[0]
+			cs.queue = cs.queue[1:]
+			cs.mu.Unlock()
+			return msg, nil
+		}
+		cs.mu.Unlock()
+
+		select {
+		case <-cs.notify:
+		case <-cs.ctx.Done():
+			cs.abandon(cs.ctx.Err())
+		case <-cs.c.readerDone:
+			// Messages may have been queued just before the reader exited.
+			cs.mu.Lock()
+			queued := len(cs.queue) > 0
+			cs.mu.Unlock()
+			if !queued {
+				cs.c.mu.Lock()
+				err := cs.c.err
+				cs.c.mu.Unlock()
+				cs.abandon(err)
+			}
+		}
+	}
+}
+
+// read unmarshals the message of frame into m and lets the server reuse its space.
+func (cs *ClientStream) read(frame *api.StreamFrame, m proto.Message) error {
+	c := cs.c
+	if err := c.region.Remap(int64(frame.MmapSize)); err != nil {
+		cs.abandon(err)
+		return err
+	}
+
+	mmap := c.region.Bytes()
+	if frame.Offset > uint64(len(mmap)) || frame.Size > uint64(len(mmap))-frame.Offset {
+		err := fmt.Errorf("stream message at offset %d with size %d exceeds mmap size %d", frame.Offset, frame.Size, len(mmap))
+		cs.abandon(err)
+		return err
+	}
+	err := proto.Unmarshal(mmap[frame.Offset:frame.Offset+frame.Size], m)
+
+	if ackErr := c.sendRequest(context.Background(), &api.StreamAck{
+		ConnectionId: c.connectionID,
+		RequestId:    cs.id,
+		Messages:     1,
+	}); ackErr != nil {
+		cs.abandon(ackErr)
+		return ackErr
+	}
+	return err
+}
+
+// growWindow replaces the window with one that holds a message of the given size. The server only
+// asks for it once it wrote the messages that are in the current window, which were read already.
+func (cs *ClientStream) growWindow(size int64) error {
+	c := cs.c
+	window, err := c.allocate(cs.ctx, max(size, defaultStreamWindow))
+	if err != nil {
+		return err
+	}
+	c.free(cs.window)
+	cs.window = window
+
+	return c.sendRequest(context.Background(), &api.StreamAck{
+		ConnectionId:   c.connectionID,
+		RequestId:      cs.id,
+		WindowOffset:   uint64(window.offset),
+		WindowCapacity: uint64(window.size),
+		MmapSize:       uint64(c.region.Len()),
+	})
+}
+
+// push queues a message received for the stream, it is called by the reader.
+func (cs *ClientStream) push(msg proto.Message) {
+	cs.mu.Lock()
+	if cs.abandoned {
+		cs.mu.Unlock()
+		if _, ok := msg.(*api.RPCResponse); ok {
+			// The server no longer writes to the slots.
+			cs.release()
+		}
+		return
+	}
+	cs.queue = append(cs.queue, msg)
+	var sent []slot
+	if _, ok := msg.(*api.RPCResponse); ok {
+		// The server no longer reads the messages sent, even if the trailer is not received yet.
+		cs.ended = true
+		sent = cs.sent
+		cs.sent = nil
+	}
+	cs.mu.Unlock()
+
+	if sent != nil {
+		cs.cancelSend()
+		for _, area := range sent {
+			cs.c.free(area)
+		}
+	}
+
+	select {
+	case cs.notify <- struct{}{}:
+	default:
+	}
+}
+
+// abandon ends the stream with err before it was read to the end and asks the server to cancel
+// it. The slots of the stream stay reserved until the trailer arrives.
+func (cs *ClientStream) abandon(err error) {
+	cs.mu.Lock()
+	if cs.err != nil {
+		cs.mu.Unlock()
+		return
+	}
+	cs.err = err
+	cs.abandoned = true
+	cs.cancelSend()
+	ended := false
+	for _, msg := range cs.queue {
+		if _, ok := msg.(*api.RPCResponse); ok {
+			ended = true
+		}
+	}
+	cs.queue = nil
+	cs.mu.Unlock()
+
+	if ended {
+		cs.release()
+		return
+	}
+	cs.c.cancel(cs.id)
+}
+
+// acked frees the slots of the given number of messages read by the server, it is called by the
+// reader.
+func (cs *ClientStream) acked(messages uint64) {
+	cs.mu.Lock()
+	n := min(messages, uint64(len(cs.sent)))
+	read := cs.sent[:n]
+	cs.sent = cs.sent[n:]
+	cs.mu.Unlock()
+
+	for _, area := range read {
+		cs.c.free(area)
+	}
+}
+
+// release frees the slots of the stream and forgets about it. The server no longer reads the
+// messages that were sent once the stream ended.
+func (cs *ClientStream) release() {
+	cs.mu.Lock()
+	if cs.released {
+		cs.mu.Unlock()
+		return
+	}
+	cs.released = true
+	sent := cs.sent
+	cs.sent = nil
+	cs.mu.Unlock()
+	cs.cancelSend()
+
+	c := cs.c
+	c.mu.Lock()
+	delete(c.streams, cs.id)
+	c.mu.Unlock()
+
+	c.free(cs.area)
+	c.free(cs.window)
+	for _, area := range sent {
+		c.free(area)
+	}
+}
+
+// enter registers a call using the region on behalf of an open stream, it fails once the client
+// is closed.
+func (c *Client) enter() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	c.inflight.Add(1)
+	return nil
+}
@@ -0,0 +1,172 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/internal/cachetest"
+	"github.com/epk/mmap-rpc/pkg/server"
+)
+
+// The streaming tests send more data than the region holds, so that the sender waits for the
+// receiver to free space.
+const (
+	streamMaxMmapSize = 256 * 1024
+	streamMessages    = 200
+	streamValueSize   = 4096
+)
+
+// streamValue returns the value of the i-th message of a stream.
+func streamValue(i int) string {
+	return fmt.Sprintf("%06d", i) + strings.Repeat("x", streamValueSize)
+}
+
+// TestServerStream watches more keys than the region holds, so that the server waits for the
+// client to acknowledge messages before writing the next ones.
+func TestServerStream(t *testing.T) {
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			srv := &server.Server{MaxMmapSize: streamMaxMmapSize}
+			impl := cachetest.NewServer()
+			cache.RegisterMmapRPCCacheServer(srv, impl)
+			cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv), transport.opts...))
+
+			var keys []string
+			for i := range streamMessages {
+				key := fmt.Sprint(i)
+				impl.SetValue(key, streamValue(i))
+				keys = append(keys, key)
+			}
+
+			stream, err := cc.Watch(context.Background(), &cache.WatchRequest{Keys: keys})
+			if err != nil {
+				t.Fatalf("Watch: %v", err)
+			}
+			for i, key := range keys {
+				event, err := stream.Recv()
+				if err != nil {
+					t.Fatalf("Recv %d: %v", i, err)
+				}
+				if event.Key != key || event.Value != streamValue(i) {
+					t.Fatalf("Recv %d: got key %q with a value of %d bytes, want key %q", i, event.Key, len(event.Value), key)
+				}
+			}
+			if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+				t.Errorf("Recv after the last event: got %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+// TestClientStream loads more values than the region holds, so that Send waits for the server
+// to read messages before writing the next ones, and checks the count returned by CloseAndRecv.
+func TestClientStream(t *testing.T) {
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			srv := &server.Server{MaxMmapSize: streamMaxMmapSize}
+			impl := cachetest.NewServer()
+			cache.RegisterMmapRPCCacheServer(srv, impl)
+			cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv), transport.opts...))
+
+			stream, err := cc.Load(context.Background())
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			for i := range streamMessages {
+				if err := stream.Send(&cache.SetRequest{Key: fmt.Sprint(i), Value: streamValue(i)}); err != nil {
+					t.Fatalf("Send %d: %v", i, err)
+				}
+			}
+			resp, err := stream.CloseAndRecv()
+			if err != nil {
+				t.Fatalf("CloseAndRecv: %v", err)
+			}
+			if resp.Count != streamMessages {
+				t.Errorf("got count %d, want %d", resp.Count, streamMessages)
+			}
+
+			for i := range streamMessages {
+				if got := impl.Value(fmt.Sprint(i)); got != streamValue(i) {
+					t.Fatalf("value %d has %d bytes, want %d", i, len(got), len(streamValue(i)))
+				}
+			}
+		})
+	}
+}
+
+// TestBidiStream looks up more values than the region holds while sending the keys, and checks
+// that the responses come in the order of the requests and that the stream ends after CloseSend.
+func TestBidiStream(t *testing.T) {
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			srv := &server.Server{MaxMmapSize: streamMaxMmapSize}
+			impl := cachetest.NewServer()
+			cache.RegisterMmapRPCCacheServer(srv, impl)
+			cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv), transport.opts...))
+
+			for i := range streamMessages {
+				impl.SetValue(fmt.Sprint(i), streamValue(i))
+			}
+
+			stream, err := cc.Lookup(context.Background())
+			if err != nil {
+				t.Fatalf("Lookup: %v", err)
+			}
+			sent := make(chan error, 1)
+			go func() {
+				for i := range streamMessages {
+					if err := stream.Send(&cache.GetRequest{Key: fmt.Sprint(i)}); err != nil {
+						sent <- fmt.Errorf("Send %d: %w", i, err)
+						return
+					}
+				}
+				sent <- stream.CloseSend()
+			}()
+
+			for i := range streamMessages {
+				resp, err := stream.Recv()
+				if err != nil {
+					t.Fatalf("Recv %d: %v", i, err)
+				}
+				if !resp.Found || resp.Value != streamValue(i) {
+					t.Fatalf("Recv %d: got a value of %d bytes starting with %.6q, want %.6q", i, len(resp.Value), resp.Value, streamValue(i))
+				}
+			}
+			if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+				t.Errorf("Recv after CloseSend: got %v, want io.EOF", err)
+			}
+			if err := <-sent; err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestEmptyMessageInFullRegion sends an empty message on a stream whose window takes the whole
+// region. It needs no room and must not wait for some.
+func TestEmptyMessageInFullRegion(t *testing.T) {
+	srv := &server.Server{MaxMmapSize: 8192}
+	impl := cachetest.NewServer()
+	cache.RegisterMmapRPCCacheServer(srv, impl)
+	cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	stream, err := cc.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := stream.Send(&cache.SetRequest{}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil || resp.Count != 1 {
+		t.Errorf("got %v, %v, want a count of 1", resp, err)
+	}
+}
@@ -0,0 +1,72 @@
+package codes
+
+import "strconv"
+
+// Code is the status code of an RPC. The codes and their meaning are the same as in gRPC.
+type Code uint32
+
+const (
+	// OK is returned on success.
+	OK Code = 0
+	// Canceled indicates the call was canceled, typically by the caller.
+	Canceled Code = 1
+	// Unknown indicates an error without a more specific code, such as a plain Go error
+	// returned by a handler.
+	Unknown Code = 2
+	// InvalidArgument indicates the caller specified an invalid argument.
+	InvalidArgument Code = 3
+	// DeadlineExceeded indicates the deadline expired before the call completed.
+	DeadlineExceeded Code = 4
+	// NotFound indicates a requested entity was not found.
+	NotFound Code = 5
+	// AlreadyExists indicates an entity the caller attempted to create already exists.
+	AlreadyExists Code = 6
+	// PermissionDenied indicates the caller is not allowed to execute the call.
+	PermissionDenied Code = 7
+	// ResourceExhausted indicates some resource has been exhausted, such as the space in the
+	// memory-mapped file.
+	ResourceExhausted Code = 8
+	// FailedPrecondition indicates the system is not in a state required for the call.
+	FailedPrecondition Code = 9
+	// Aborted indicates the call was aborted, typically due to a concurrency issue.
+	Aborted Code = 10
+	// OutOfRange indicates the call was attempted past the valid range.
+	OutOfRange Code = 11
+	// Unimplemented indicates the method is not implemented or not registered.
+	Unimplemented Code = 12
+	// Internal indicates an internal error in the framework or the handler.
+	Internal Code = 13
+	// Unavailable indicates the service is currently unavailable.
+	Unavailable Code = 14
+	// DataLoss indicates unrecoverable data loss or corruption.
+	DataLoss Code = 15
+	// Unauthenticated indicates the caller does not have valid credentials.
+	Unauthenticated Code = 16
+)
+
+var names = [...]string{
+	OK:                 "OK",
+	Canceled:           "Canceled",
+	Unknown:            "Unknown",
+	InvalidArgument:    "InvalidArgument",
+	DeadlineExceeded:   "DeadlineExceeded",
+	NotFound:           "NotFound",
+	AlreadyExists:      "AlreadyExists",
+	PermissionDenied:   "PermissionDenied",
+	ResourceExhausted:  "ResourceExhausted",
+	FailedPrecondition: "FailedPrecondition",
+	Aborted:            "Aborted",
+	OutOfRange:         "OutOfRange",
+	Unimplemented:      "Unimplemented",
+	Internal:           "Internal",
+	Unavailable:        "Unavailable",
+	DataLoss:           "DataLoss",
+	Unauthenticated:    "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if int(c) < len(names) {
+		return names[c]
+	}
+	return "Code(" + strconv.FormatUint(uint64(c), 10) + ")"
+}
@@ -0,0 +1,100 @@
+// Package metadata carries the headers and trailers of RPCs, such as request IDs, tenant IDs, auth
+// tokens or trace context. Clients attach metadata to the context of their calls, and handlers
+// read it from the context of the call, like with gRPC.
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// MD is a set of metadata, mapping lowercase keys to their value.
+type MD map[string][]byte
+
+// New returns the metadata holding the key-value pairs of m.
+func New(m map[string]string) MD {
+	md := make(MD, len(m))
+	for key, value := range m {
+		md.Set(key, []byte(value))
+	}
+	return md
+}
+
+// Pairs returns the metadata holding the key-value pairs of kv, which alternates keys and values.
+// It panics if kv has an odd length. A key given more than once takes its last value.
+func Pairs(kv ...string) MD {
+	if len(kv)%2 == 1 {
+		panic(fmt.Sprintf("metadata: Pairs got an odd number of arguments: %d", len(kv)))
+	}
+	md := make(MD, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		md.Set(kv[i], []byte(kv[i+1]))
+	}
+	return md
+}
+
+// Get returns the value of key, nil if it is not set.
+func (md MD) Get(key string) []byte {
+	return md[strings.ToLower(key)]
+}
+
+// Set sets the value of key.
+func (md MD) Set(key string, value []byte) {
+	md[strings.ToLower(key)] = value
+}
+
+// Copy returns a copy of md, the values are shared.
+func (md MD) Copy() MD {
+	return maps.Clone(md)
+}
+
+// Join returns the metadata holding the pairs of all of mds. A key set in more than one of them
+// takes its value from the last one.
+func Join(mds ...MD) MD {
+	md := MD{}
+	for _, m := range mds {
+		maps.Copy(md, m)
+	}
+	return md
+}
+
+type contextKey int
+
+const (
+	outgoingKey contextKey = iota
+	incomingKey
+)
+
+// NewOutgoingContext returns a copy of ctx whose calls send md to the server, replacing the
+// metadata already attached to ctx.
+func NewOutgoingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, outgoingKey, md)
+}
+
+// AppendToOutgoingContext returns a copy of ctx whose calls also send the key-value pairs of kv, as
+// with Pairs. The metadata already attached to ctx is not modified.
+func AppendToOutgoingContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromOutgoingContext(ctx)
+	return NewOutgoingContext(ctx, Join(md, Pairs(kv...)))
+}
+
+// FromOutgoingContext returns the metadata the calls made with ctx send to the server. It must
+// not be modified, see NewOutgoingContext and AppendToOutgoingContext.
+func FromOutgoingContext(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(outgoingKey).(MD)
+	return md, ok
+}
+
+// NewIncomingContext returns a copy of ctx holding the metadata received from the client. The
+// server sets it up for the context of every call.
+func NewIncomingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, incomingKey, md)
+}
+
+// FromIncomingContext returns the metadata sent by the client of the call handled with ctx.
+func FromIncomingContext(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(incomingKey).(MD)
+	return md, ok
+}
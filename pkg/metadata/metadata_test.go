@@ -0,0 +1,63 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKeysAreLowercase(t *testing.T) {
+	md := Pairs("Request-ID", "1", "request-id", "2")
+	if len(md) != 1 {
+		t.Errorf("got %d keys, want 1", len(md))
+	}
+	if got := string(md.Get("REQUEST-ID")); got != "2" {
+		t.Errorf("got %q, want the last value %q", got, "2")
+	}
+
+	md = New(map[string]string{"Tenant": "acme"})
+	if got := string(md["tenant"]); got != "acme" {
+		t.Errorf("got %q, want %q", got, "acme")
+	}
+}
+
+func TestPairsOddArguments(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Pairs did not panic for an odd number of arguments")
+		}
+	}()
+	Pairs("key")
+}
+
+func TestJoin(t *testing.T) {
+	md := Join(Pairs("a", "1", "b", "1"), Pairs("b", "2"), nil)
+	if got := string(md.Get("a")); got != "1" {
+		t.Errorf("got a=%q, want %q", got, "1")
+	}
+	if got := string(md.Get("b")); got != "2" {
+		t.Errorf("got b=%q, want the value of the last metadata %q", got, "2")
+	}
+}
+
+func TestOutgoingContext(t *testing.T) {
+	if _, ok := FromOutgoingContext(context.Background()); ok {
+		t.Error("a context without metadata has outgoing metadata")
+	}
+
+	parent := NewOutgoingContext(context.Background(), Pairs("a", "1"))
+	child := AppendToOutgoingContext(parent, "b", "2")
+
+	md, _ := FromOutgoingContext(child)
+	if string(md.Get("a")) != "1" || string(md.Get("b")) != "2" {
+		t.Errorf("got %v, want a=1 and b=2", md)
+	}
+	md, _ = FromOutgoingContext(parent)
+	if md.Get("b") != nil {
+		t.Errorf("AppendToOutgoingContext modified the metadata of the parent context: %v", md)
+	}
+
+	// The incoming metadata of a call is separate from the metadata of the calls it makes.
+	if _, ok := FromIncomingContext(child); ok {
+		t.Error("outgoing metadata is seen as incoming")
+	}
+}
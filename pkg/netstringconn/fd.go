@@ -0,0 +1,98 @@
+package netstringconn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/kyrylo/netstring"
+)
+
+// maxFDs is the number of file descriptors that can be received with a single read.
+const maxFDs = 4
+
+// NewNetstringConnWithFDs is like NewNetstringConn, but also receives the file descriptors passed
+// by the peer with SCM_RIGHTS, see TakeFDs. Connections created with NewNetstringConn discard
+// them, so a peer cannot make the process run out of file descriptors.
+func NewNetstringConnWithFDs(conn *net.UnixConn) *NetstringConn {
+	nc := &NetstringConn{conn: conn}
+	nc.reader = bufio.NewReader(&fdReader{conn: conn, nc: nc, oob: make([]byte, syscall.CmsgSpace(maxFDs*4))})
+	return nc
+}
+
+// TakeFDs returns the file descriptors received so far and forgets about them, the caller is
+// responsible for closing them. A descriptor is received by the Read of the message it was sent
+// with, or an earlier one.
+func (nc *NetstringConn) TakeFDs() []int {
+	nc.fdMu.Lock()
+	defer nc.fdMu.Unlock()
+
+	fds := nc.fds
+	nc.fds = nil
+	return fds
+}
+
+// WriteFD is like Write, but passes the file descriptor fd to the peer along with data.
+func (nc *NetstringConn) WriteFD(data []byte, fd int) error {
+	unixConn, ok := nc.conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("cannot pass file descriptors over %T", nc.conn)
+	}
+
+	nc.writeMu.Lock()
+	defer nc.writeMu.Unlock()
+
+	packed := netstring.Pack(data)
+	n, _, err := unixConn.WriteMsgUnix(packed, syscall.UnixRights(fd), nil)
+	if err != nil {
+		return err
+	}
+	// The descriptor went along with the first byte, the rest may still need to be written.
+	_, err = nc.conn.Write(packed[n:])
+	return err
+}
+
+// fdReader reads from a Unix socket and collects the file descriptors passed with SCM_RIGHTS.
+type fdReader struct {
+	conn *net.UnixConn
+	nc   *NetstringConn
+	oob  []byte
+}
+
+func (r *fdReader) Read(p []byte) (int, error) {
+	n, oobn, _, _, err := r.conn.ReadMsgUnix(p, r.oob)
+	if n < 0 {
+		// ReadMsgUnix reports -1 along with some errors, io.Reader must not.
+		n = 0
+	}
+	if oobn > 0 {
+		if fds, parseErr := parseRights(r.oob[:oobn]); parseErr != nil {
+			err = errors.Join(err, parseErr)
+		} else {
+			r.nc.fdMu.Lock()
+			r.nc.fds = append(r.nc.fds, fds...)
+			r.nc.fdMu.Unlock()
+		}
+	}
+	return n, err
+}
+
+// parseRights returns the file descriptors in the control messages of oob.
+func parseRights(oob []byte) ([]int, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse control message: %w", err)
+	}
+
+	var fds []int
+	for _, msg := range msgs {
+		rights, err := syscall.ParseUnixRights(&msg)
+		if err != nil {
+			continue
+		}
+		fds = append(fds, rights...)
+	}
+	return fds, nil
+}
@@ -2,15 +2,28 @@ package netstringconn
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/kyrylo/netstring"
 )
 
 // NetstringConn is a wrapper around net.Conn that reads and writes data in netstring format.
+// Write may be called from multiple goroutines, Read may not.
 type NetstringConn struct {
 	conn   net.Conn
 	reader *bufio.Reader
+
+	writeMu sync.Mutex
+
+	// fds holds the file descriptors received with NewNetstringConnWithFDs until TakeFDs.
+	fdMu sync.Mutex
+	fds  []int
 }
 
 func NewNetstringConn(conn net.Conn) *NetstringConn {
@@ -25,10 +38,57 @@ func (nc *NetstringConn) Read() ([]byte, error) {
 }
 
 func (nc *NetstringConn) Write(data []byte) error {
+	nc.writeMu.Lock()
+	defer nc.writeMu.Unlock()
+
 	_, err := nc.conn.Write(netstring.Pack(data))
 	return err
 }
 
+// WriteContext is like Write, but fails once ctx is canceled or its deadline expires. If that
+// happens after part of the message was written, the connection is closed: the peer could not tell
+// where the next message starts.
+func (nc *NetstringConn) WriteContext(ctx context.Context, data []byte) error {
+	if ctx.Done() == nil {
+		return nc.Write(data)
+	}
+
+	nc.writeMu.Lock()
+	defer nc.writeMu.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	if err := nc.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	defer nc.conn.SetWriteDeadline(time.Time{})
+
+	// A deadline in the past interrupts a write blocked on a full socket buffer when ctx is
+	// canceled. The deadline is reset once the function stopped or returned.
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(interrupted)
+		nc.conn.SetWriteDeadline(time.Unix(1, 0))
+	})
+	defer func() {
+		if !stop() {
+			<-interrupted
+		}
+	}()
+
+	n, err := nc.conn.Write(netstring.Pack(data))
+	if err != nil && n > 0 {
+		nc.conn.Close()
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) && errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Close closes the connection along with the file descriptors that were not taken.
 func (nc *NetstringConn) Close() error {
+	for _, fd := range nc.TakeFDs() {
+		syscall.Close(fd)
+	}
 	return nc.conn.Close()
 }
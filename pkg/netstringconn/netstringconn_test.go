@@ -0,0 +1,94 @@
+package netstringconn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWriteContextTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	nc := NewNetstringConn(client)
+	defer nc.Close()
+
+	// Nothing is read, the message is not written at all and the framing is intact.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := nc.WriteContext(ctx, []byte("hello")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v, want os.ErrDeadlineExceeded", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- nc.Write([]byte("hello"))
+	}()
+	msg, err := NewNetstringConn(server).Read()
+	if err != nil {
+		t.Fatalf("failed to read after the timeout: %v", err)
+	}
+	if !bytes.Equal(msg, []byte("hello")) {
+		t.Errorf("got %q, want %q", msg, "hello")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("failed to write after the timeout: %v", err)
+	}
+}
+
+func TestWriteContextCanceled(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	nc := NewNetstringConn(client)
+	defer nc.Close()
+
+	// Nothing is read and ctx has no deadline, the write is only interrupted by the cancellation.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := nc.WriteContext(ctx, []byte("hello")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	// The deadline set to interrupt the write does not outlive it.
+	done := make(chan error, 1)
+	go func() {
+		done <- nc.WriteContext(context.Background(), []byte("hello"))
+	}()
+	msg, err := NewNetstringConn(server).Read()
+	if err != nil {
+		t.Fatalf("failed to read after the cancellation: %v", err)
+	}
+	if !bytes.Equal(msg, []byte("hello")) {
+		t.Errorf("got %q, want %q", msg, "hello")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("failed to write after the cancellation: %v", err)
+	}
+}
+
+func TestWriteContextPartialWrite(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	nc := NewNetstringConn(client)
+	defer nc.Close()
+
+	// Only the start of the message is read, the peer could no longer find the next message.
+	go func() {
+		io.ReadFull(server, make([]byte, 4))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := nc.WriteContext(ctx, []byte("hello")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v, want os.ErrDeadlineExceeded", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := nc.WriteContext(ctx, []byte("hello")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("write after a partial write: got %v, want the connection closed", err)
+	}
+}
@@ -0,0 +1,81 @@
+package region
+
+import "sort"
+
+// extent is a range of bytes within a region.
+type extent struct {
+	offset int64
+	size   int64
+}
+
+// Allocator hands out non-overlapping slots of a region using a first-fit free list.
+// It is not safe for concurrent use.
+type Allocator struct {
+	size int64
+	free []extent // sorted by offset, adjacent extents are coalesced
+}
+
+// NewAllocator returns an Allocator managing the first size bytes of a region.
+func NewAllocator(size int64) *Allocator {
+	a := &Allocator{}
+	a.Extend(size)
+	return a
+}
+
+// Alloc reserves size bytes and returns the offset of the slot, aligned to Alignment.
+// It returns false if no free extent is large enough.
+func (a *Allocator) Alloc(size int64) (int64, bool) {
+	size = Align(size)
+	for i, e := range a.free {
+		if e.size < size {
+			continue
+		}
+
+		if e.size == size {
+			a.free = append(a.free[:i], a.free[i+1:]...)
+		} else {
+			a.free[i] = extent{offset: e.offset + size, size: e.size - size}
+		}
+		return e.offset, true
+	}
+	return 0, false
+}
+
+// Free releases a slot returned by Alloc.
+func (a *Allocator) Free(offset, size int64) {
+	a.insert(extent{offset: offset, size: Align(size)})
+}
+
+// Extend adds the space between the current size and size to the free list.
+func (a *Allocator) Extend(size int64) {
+	size = size &^ (Alignment - 1)
+	if size <= a.size {
+		return
+	}
+	a.insert(extent{offset: a.size, size: size - a.size})
+	a.size = size
+}
+
+// Size returns the number of bytes managed by the allocator.
+func (a *Allocator) Size() int64 {
+	return a.size
+}
+
+func (a *Allocator) insert(e extent) {
+	i := sort.Search(len(a.free), func(i int) bool { return a.free[i].offset > e.offset })
+
+	// Coalesce with the following extent.
+	if i < len(a.free) && e.offset+e.size == a.free[i].offset {
+		e.size += a.free[i].size
+		a.free = append(a.free[:i], a.free[i+1:]...)
+	}
+	// Coalesce with the preceding extent.
+	if i > 0 && a.free[i-1].offset+a.free[i-1].size == e.offset {
+		a.free[i-1].size += e.size
+		return
+	}
+
+	a.free = append(a.free, extent{})
+	copy(a.free[i+1:], a.free[i:])
+	a.free[i] = e
+}
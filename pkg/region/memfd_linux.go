@@ -0,0 +1,34 @@
+package region
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// CreateMemfd creates an anonymous file of the given size with memfd_create and maps it into
+// memory. The file never appears in the filesystem, it is shared by passing its descriptor.
+//
+// The file is sealed against shrinking and growing, so that a peer cannot truncate it under the
+// mapping and make accesses fault. Its size is therefore fixed and it should be created at its
+// maximum size, memory is only allocated for the pages that are used.
+func CreateMemfd(name string, size int64) (*Region, error) {
+	fd, err := unix.MemfdCreate(name, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memfd: %w", err)
+	}
+
+	r, err := create(os.NewFile(uintptr(fd), "memfd:"+name), size)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := unix.FcntlInt(r.file.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_SEAL); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to seal memfd: %w", err)
+	}
+	r.sealed = true
+
+	return r, nil
+}
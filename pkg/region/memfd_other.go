@@ -0,0 +1,8 @@
+//go:build !linux
+
+package region
+
+// CreateMemfd is only supported on Linux.
+func CreateMemfd(name string, size int64) (*Region, error) {
+	return nil, ErrMemfdUnsupported
+}
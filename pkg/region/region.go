@@ -0,0 +1,291 @@
+package region
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tysonmote/gommap"
+)
+
+// ErrPayloadTooLarge is returned when a request or response does not fit in the maximum region size.
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// ErrClosed is returned when growing or remapping a region after Close.
+var ErrClosed = errors.New("region closed")
+
+// ErrTruncated is returned by Validate when the backing file no longer covers the mapping.
+var ErrTruncated = errors.New("mmap file truncated")
+
+// ErrMemfdUnsupported is returned by CreateMemfd on platforms without memfd_create.
+var ErrMemfdUnsupported = errors.New("memfd is not supported on this platform")
+
+// Region is a memory-mapped file shared between a client and the server.
+// It is safe for concurrent use.
+type Region struct {
+	file    *os.File
+	maxSize int64
+
+	mu   sync.RWMutex
+	mmap gommap.MMap
+	// old holds the mappings replaced by Grow and Remap. They stay mapped until Close so that
+	// slices handed out by Bytes remain valid, and since they map the same file they observe
+	// the same data as the current mapping.
+	old    []gommap.MMap
+	closed bool
+	// sealed is set for memfds that cannot change size.
+	sealed bool
+}
+
+// Create creates a new file of the given size with the given permissions and maps it into memory.
+// It fails if the file already exists, so that a file planted by another process is never used.
+func Create(filename string, size int64, perm os.FileMode) (*Region, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mmap file: %w", err)
+	}
+
+	// The umask may have cleared bits of perm.
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to chmod mmap file: %w", err)
+	}
+
+	return create(file, size)
+}
+
+// create truncates file to size and maps it into memory, file is closed on failure.
+func create(file *os.File, size int64) (*Region, error) {
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate mmap file: %w", err)
+	}
+
+	mmap, err := mapFile(file, size)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Region{file: file, mmap: mmap}, nil
+}
+
+// Open maps an existing file into memory.
+func Open(filename string) (*Region, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mmap file: %w", err)
+	}
+
+	return OpenFile(file)
+}
+
+// OpenFile maps an open file, such as a memfd received from the server, into memory. The region
+// takes ownership of file, which is closed on failure.
+func OpenFile(file *os.File) (*Region, error) {
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat mmap file: %w", err)
+	}
+
+	mmap, err := mapFile(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Region{file: file, mmap: mmap}, nil
+}
+
+// Bytes returns the current mapping. The returned slice stays valid until Close, but does
+// not cover space added by later calls to Grow and Remap.
+func (r *Region) Bytes() []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.mmap
+}
+
+// SetMaxSize limits how far Grow may extend the region and Remap may map it. Zero means no limit.
+// It must be called before the region is shared between goroutines.
+func (r *Region) SetMaxSize(size int64) {
+	r.maxSize = size
+}
+
+// MaxSize returns the size limit set by SetMaxSize.
+func (r *Region) MaxSize() int64 {
+	return r.maxSize
+}
+
+// Len returns the size of the current mapping.
+func (r *Region) Len() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.mmap))
+}
+
+// Chown changes the owner of the backing file.
+func (r *Region) Chown(uid, gid int) error {
+	if err := r.file.Chown(uid, gid); err != nil {
+		return fmt.Errorf("failed to chown mmap file: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that the backing file still covers the current mapping. Accessing a mapping
+// past the end of its file raises SIGBUS, which a peer can provoke by truncating a shared file, so
+// it should be called before accessing the mapping. Sealed memfds cannot shrink and are not checked.
+func (r *Region) Validate() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.sealed {
+		return nil
+	}
+
+	info, err := r.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat mmap file: %w", err)
+	}
+	if info.Size() < int64(len(r.mmap)) {
+		return fmt.Errorf("%w: %d bytes left of %d mapped", ErrTruncated, info.Size(), len(r.mmap))
+	}
+	return nil
+}
+
+// Fd returns the file descriptor of the backing file, to pass it to the peer.
+func (r *Region) Fd() uintptr {
+	return r.file.Fd()
+}
+
+// Name returns the name of the backing file.
+func (r *Region) Name() string {
+	return r.file.Name()
+}
+
+// Grow extends the backing file so that it can hold at least size bytes and remaps it.
+// The region at least doubles in size to amortize the cost of growing, up to its maximum size.
+// ErrPayloadTooLarge is returned if size exceeds the maximum size.
+func (r *Region) Grow(size int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+	if size <= int64(len(r.mmap)) {
+		return nil
+	}
+	if r.maxSize > 0 && size > r.maxSize {
+		return fmt.Errorf("%w: %d bytes exceeds maximum mmap size %d", ErrPayloadTooLarge, size, r.maxSize)
+	}
+
+	newSize := roundUp(max(size, 2*int64(len(r.mmap))))
+	if r.maxSize > 0 {
+		newSize = min(newSize, r.maxSize)
+	}
+	if err := r.file.Truncate(newSize); err != nil {
+		return fmt.Errorf("failed to truncate mmap file: %w", err)
+	}
+
+	return r.remap(newSize)
+}
+
+// Remap maps size bytes of the backing file, which must already have been extended by the peer.
+// Since the replaced mappings are kept until Close, sizes are checked before mapping: size may not
+// exceed the maximum size, ErrPayloadTooLarge is returned otherwise, nor the size of the backing
+// file, ErrTruncated is returned otherwise.
+func (r *Region) Remap(size int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+	if size <= int64(len(r.mmap)) {
+		return nil
+	}
+	if r.maxSize > 0 && size > r.maxSize {
+		return fmt.Errorf("%w: %d bytes exceeds maximum mmap size %d", ErrPayloadTooLarge, size, r.maxSize)
+	}
+	info, err := r.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat mmap file: %w", err)
+	}
+	if info.Size() < size {
+		return fmt.Errorf("%w: %d bytes in file, cannot map %d", ErrTruncated, info.Size(), size)
+	}
+
+	return r.remap(size)
+}
+
+func (r *Region) remap(size int64) error {
+	if r.closed {
+		return ErrClosed
+	}
+	if size <= int64(len(r.mmap)) {
+		return nil
+	}
+
+	mmap, err := mapFile(r.file, size)
+	if err != nil {
+		return err
+	}
+
+	r.old = append(r.old, r.mmap)
+	r.mmap = mmap
+
+	return nil
+}
+
+// Close unmaps the region and closes the backing file, it does nothing if the region is already
+// closed. Slices returned by Bytes must not be used after Close.
+func (r *Region) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	var errs []error
+	for _, mmap := range append(r.old, r.mmap) {
+		if err := mmap.UnsafeUnmap(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unmap mmap file: %w", err))
+		}
+	}
+	r.mmap = nil
+	r.old = nil
+
+	if err := r.file.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close mmap file: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func mapFile(file *os.File, size int64) (gommap.MMap, error) {
+	mmap, err := gommap.MapRegion(file.Fd(), 0, size, gommap.PROT_READ|gommap.PROT_WRITE, gommap.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mmap file: %w", err)
+	}
+	return mmap, nil
+}
+
+// Alignment is the alignment of the data areas within a region.
+const Alignment = 64
+
+// Align rounds offset up to a multiple of Alignment.
+func Align(offset int64) int64 {
+	return (offset + Alignment - 1) &^ (Alignment - 1)
+}
+
+// roundUp rounds size up to a multiple of the page size.
+func roundUp(size int64) int64 {
+	pageSize := int64(os.Getpagesize())
+	return (size + pageSize - 1) / pageSize * pageSize
+}
@@ -0,0 +1,52 @@
+package region
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemapBounds(t *testing.T) {
+	pageSize := int64(os.Getpagesize())
+
+	r, err := Create(filepath.Join(t.TempDir(), "region.mmap"), pageSize, 0o600)
+	if err != nil {
+		t.Fatalf("failed to create region: %v", err)
+	}
+	defer r.Close()
+	r.SetMaxSize(4 * pageSize)
+
+	if err := r.Remap(8 * pageSize); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Remap past the maximum size: got %v, want ErrPayloadTooLarge", err)
+	}
+	if err := r.Remap(2 * pageSize); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Remap past the end of the file: got %v, want ErrTruncated", err)
+	}
+	if got := r.Len(); got != pageSize {
+		t.Errorf("got length %d after rejected remaps, want %d", got, pageSize)
+	}
+	if n := len(r.old); n != 0 {
+		t.Errorf("rejected remaps kept %d old mappings", n)
+	}
+
+	if err := os.Truncate(r.Name(), 2*pageSize); err != nil {
+		t.Fatalf("failed to extend file: %v", err)
+	}
+	if err := r.Remap(2 * pageSize); err != nil {
+		t.Fatalf("Remap within the file: %v", err)
+	}
+	if got := r.Len(); got != 2*pageSize {
+		t.Errorf("got length %d, want %d", got, 2*pageSize)
+	}
+	if err := r.Remap(pageSize); err != nil {
+		t.Errorf("Remap to a smaller size: %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("failed to close region: %v", err)
+	}
+	if err := r.Remap(4 * pageSize); !errors.Is(err, ErrClosed) {
+		t.Errorf("Remap after Close: got %v, want ErrClosed", err)
+	}
+}
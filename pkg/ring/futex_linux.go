@@ -0,0 +1,36 @@
+package ring
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// FutexSupported reports whether Sleep and Wake are implemented on this platform.
+const FutexSupported = true
+
+// Operations of the futex system call. The private variants cannot be used, the ring is shared
+// between processes.
+const (
+	futexWait = 0
+	futexWake = 1
+)
+
+// sleepTimeout bounds a single Sleep, so that the consumer eventually checks whether it should
+// stop even if the peer keeps the waiting flag set.
+const sleepTimeout = 100 * time.Millisecond
+
+// Sleep blocks the parked consumer until the producer or Interrupt wakes it up. It may return
+// spuriously, the consumer then polls the ring again and parks anew.
+func (r *Ring) Sleep() {
+	// The kernel only puts the thread to sleep if the flag is still set, so a wake-up that
+	// happens in between is not lost.
+	timeout := unix.NsecToTimespec(int64(sleepTimeout))
+	unix.Syscall6(unix.SYS_FUTEX, uintptr(unsafe.Pointer(r.waiting)), futexWait, 1, uintptr(unsafe.Pointer(&timeout)), 0, 0)
+}
+
+// Wake wakes up the consumer sleeping in Sleep, after Push reported that it must be woken up.
+func (r *Ring) Wake() {
+	unix.Syscall6(unix.SYS_FUTEX, uintptr(unsafe.Pointer(r.waiting)), futexWake, 1, 0, 0, 0)
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package ring
+
+// FutexSupported reports whether Sleep and Wake are implemented on this platform.
+const FutexSupported = false
+
+// Sleep is only supported on Linux, elsewhere it returns right away.
+func (r *Ring) Sleep() {}
+
+// Wake is only supported on Linux.
+func (r *Ring) Wake() {}
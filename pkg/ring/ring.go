@@ -0,0 +1,206 @@
+// Package ring implements a single-producer single-consumer queue of messages in memory shared
+// between two processes.
+//
+// The head and tail indices live in the shared memory and are updated atomically, the producer
+// appends messages and publishes them by advancing the tail, and the consumer reads them and
+// releases their space by advancing the head. Each side keeps its own index privately and only
+// reads the other one from the shared memory, so a misbehaving peer cannot make it overrun the
+// ring.
+//
+// A consumer that finds the ring empty first polls it with Spin and then parks, the producer
+// then learns from Push that it has to wake it up, either by other means such as a message on a
+// socket, or with Wake on platforms where the consumer can Sleep on a futex.
+package ring
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	// cacheLine separates the indices so that producer and consumer do not share a cache line.
+	cacheLine = 64
+
+	headOffset    = 0
+	tailOffset    = cacheLine
+	waitingOffset = 2 * cacheLine
+	headerSize    = 3 * cacheLine
+
+	// Messages are prefixed by their length and start at multiples of recordAlign.
+	lenSize     = 4
+	recordAlign = 8
+	// wrapMarker in place of a length tells the consumer to continue at the start of the ring.
+	wrapMarker = ^uint32(0)
+
+	// Bounds of the number of polls made by Spin, which adapts to how fast messages arrive.
+	minSpin = 8
+	maxSpin = 1024
+)
+
+// Size is the number of bytes of shared memory used by a ring, including its header.
+const Size = 64 * 1024
+
+// capacity is the number of bytes available for messages.
+const capacity = Size - headerSize
+
+// ErrCorrupt is returned by Pop when the shared memory does not hold a valid ring.
+var ErrCorrupt = errors.New("ring corrupted")
+
+// Ring is one side of a ring in shared memory, either the producer or the consumer.
+// It is not safe for concurrent use.
+type Ring struct {
+	head    *atomic.Uint64
+	tail    *atomic.Uint64
+	waiting *atomic.Uint32
+	data    []byte
+
+	// pos is the private copy of the index owned by this side, the tail for the producer and
+	// the head for the consumer.
+	pos uint64
+	// spin is the number of polls the next Spin makes.
+	spin int
+}
+
+// NewProducer returns the producer side of the ring stored in buf, which must be Size bytes
+// long, 64-byte aligned and zeroed before first use.
+func NewProducer(buf []byte) *Ring {
+	r := newRing(buf)
+	r.pos = r.tail.Load()
+	return r
+}
+
+// NewConsumer returns the consumer side of the ring stored in buf, see NewProducer.
+func NewConsumer(buf []byte) *Ring {
+	r := newRing(buf)
+	r.pos = r.head.Load()
+	return r
+}
+
+func newRing(buf []byte) *Ring {
+	if len(buf) < Size {
+		panic(fmt.Sprintf("ring: buffer of %d bytes is smaller than %d", len(buf), Size))
+	}
+	if uintptr(unsafe.Pointer(&buf[0]))%cacheLine != 0 {
+		panic("ring: buffer is not aligned")
+	}
+
+	return &Ring{
+		spin:    minSpin,
+		head:    (*atomic.Uint64)(unsafe.Pointer(&buf[headOffset])),
+		tail:    (*atomic.Uint64)(unsafe.Pointer(&buf[tailOffset])),
+		waiting: (*atomic.Uint32)(unsafe.Pointer(&buf[waitingOffset])),
+		data:    buf[headerSize:Size],
+	}
+}
+
+// Push appends msg to the ring. It returns false if there is not enough free space, the message
+// is then not queued. wake reports whether the consumer is parked and must be woken up.
+func (r *Ring) Push(msg []byte) (ok, wake bool) {
+	used := r.pos - r.head.Load()
+	if used > capacity {
+		// The consumer reported a head past the tail.
+		return false, false
+	}
+	free := capacity - used
+
+	need := align(lenSize + uint64(len(msg)))
+	at := r.pos % capacity
+	var pad uint64
+	if capacity-at < need {
+		// The message does not fit before the end, it starts over at the beginning.
+		pad = capacity - at
+	}
+	if pad+need > free {
+		return false, false
+	}
+
+	if pad > 0 {
+		binary.LittleEndian.PutUint32(r.data[at:], wrapMarker)
+		at = 0
+	}
+	binary.LittleEndian.PutUint32(r.data[at:], uint32(len(msg)))
+	copy(r.data[at+lenSize:], msg)
+
+	r.pos += pad + need
+	r.tail.Store(r.pos)
+
+	return true, r.waiting.CompareAndSwap(1, 0)
+}
+
+// Pop removes the next message from the ring and returns a copy of it, or nil if the ring is empty.
+func (r *Ring) Pop() ([]byte, error) {
+	for {
+		avail := r.tail.Load() - r.pos
+		if avail == 0 {
+			return nil, nil
+		}
+		if avail > capacity || avail < recordAlign {
+			return nil, fmt.Errorf("%w: %d bytes available", ErrCorrupt, avail)
+		}
+
+		at := r.pos % capacity
+		n := binary.LittleEndian.Uint32(r.data[at:])
+		if n == wrapMarker {
+			if capacity-at > avail {
+				return nil, fmt.Errorf("%w: wrap past the tail", ErrCorrupt)
+			}
+			r.pos += capacity - at
+			r.head.Store(r.pos)
+			continue
+		}
+
+		need := align(lenSize + uint64(n))
+		if need > avail || need > capacity-at {
+			return nil, fmt.Errorf("%w: message of %d bytes", ErrCorrupt, n)
+		}
+		msg := make([]byte, n)
+		copy(msg, r.data[at+lenSize:])
+
+		r.pos += need
+		r.head.Store(r.pos)
+		return msg, nil
+	}
+}
+
+// Park marks the consumer as waiting for a wake-up. It returns false if messages arrived in the
+// meantime, in which case the consumer must not wait and is no longer marked as waiting.
+func (r *Ring) Park() bool {
+	r.waiting.Store(1)
+	if r.tail.Load() == r.pos {
+		return true
+	}
+	r.waiting.CompareAndSwap(1, 0)
+	return false
+}
+
+// Interrupt clears the waiting flag of a parked consumer and wakes it up from Sleep, for example
+// to let it notice that the connection is closed. Use it from a goroutine other than the
+// consumer's, after making the reason visible to the consumer.
+func (r *Ring) Interrupt() {
+	r.waiting.Store(0)
+	r.Wake()
+}
+
+// Spin polls the empty ring for a short while and reports whether messages arrived, in which case
+// the consumer does not need to park. The time spent polling grows while messages keep arriving
+// during the spin and shrinks while they do not, so that a consumer that would sleep anyway
+// does not burn CPU.
+func (r *Ring) Spin() bool {
+	for range r.spin {
+		if r.tail.Load() != r.pos {
+			r.spin = min(2*r.spin, maxSpin)
+			return true
+		}
+		runtime.Gosched()
+	}
+	r.spin = max(r.spin/2, minSpin)
+	return false
+}
+
+func align(n uint64) uint64 {
+	return (n + recordAlign - 1) &^ (recordAlign - 1)
+}
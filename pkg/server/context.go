@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/metadata"
+	"github.com/epk/mmap-rpc/pkg/status"
+)
+
+type contextKey int
+
+const (
+	connectionIDKey contextKey = iota
+	methodKey
+	peerKey
+	rawRequestKey
+	responseBufferKey
+	callMetadataKey
+)
+
+// Peer describes the process on the other end of the Unix socket.
+type Peer struct {
+	// Addr is the address of the client's end of the socket, usually unnamed.
+	Addr net.Addr
+	// Creds are the credentials of the client process when it connected, read with SO_PEERCRED.
+	// They are nil on platforms that do not support it.
+	Creds *Credentials
+}
+
+// Credentials identify the process on the other end of the Unix socket.
+type Credentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// ConnectionID returns the ID of the mmap-rpc connection the call handled with ctx was made on.
+func ConnectionID(ctx context.Context) (string, bool) {
+	connID, ok := ctx.Value(connectionIDKey).(string)
+	return connID, ok
+}
+
+// Method returns the fully qualified name of the method handled with ctx.
+func Method(ctx context.Context) (string, bool) {
+	method, ok := ctx.Value(methodKey).(string)
+	return method, ok
+}
+
+// PeerFromContext returns the peer of the call handled with ctx.
+func PeerFromContext(ctx context.Context) (*Peer, bool) {
+	peer, ok := ctx.Value(peerKey).(*Peer)
+	return peer, ok
+}
+
+// RawRequest returns the serialized request of the call handled with ctx where it lies in the
+// memory shared with the client, without copying it.
+//
+// The slice aliases the client's memory-mapped file:
+//   - It is only valid until the handler returns, the client reuses the memory afterwards. Copy
+//     the parts that are kept longer, and do not return it or a slice of it as the response.
+//   - The client can still write to it, so it must be treated as untrusted input that may change
+//     while it is read. Values derived from it have to be validated after they are read.
+//   - Reading it may fault if the client truncates the file, the call then fails with DataLoss.
+func RawRequest(ctx context.Context) ([]byte, bool) {
+	data, ok := ctx.Value(rawRequestKey).([]byte)
+	return data, ok
+}
+
+// ResponseBuffer returns an empty slice whose capacity is the area the client reserved for the
+// response of the call handled with ctx, nil if there is none. A handler that appends its
+// serialized response to it, for example with proto.MarshalOptions.MarshalAppend, and returns the
+// result has the response written in place, the server does not copy it again. Responses that
+// do not fit are reallocated by append and sent as usual. The aliasing rules of RawRequest apply.
+func ResponseBuffer(ctx context.Context) []byte {
+	buf, _ := ctx.Value(responseBufferKey).([]byte)
+	return buf
+}
+
+// SetHeader adds md to the header sent to the client along with the response of the call handled
+// with ctx, or with the trailer of a stream. The metadata sent by the client is read with
+// metadata.FromIncomingContext.
+func SetHeader(ctx context.Context, md metadata.MD) error {
+	return setMetadata(ctx, md, func(m *callMetadata) *metadata.MD { return &m.header })
+}
+
+// SetTrailer adds md to the trailer sent to the client along with the response of the call
+// handled with ctx, or with the trailer of a stream.
+func SetTrailer(ctx context.Context, md metadata.MD) error {
+	return setMetadata(ctx, md, func(m *callMetadata) *metadata.MD { return &m.trailer })
+}
+
+// callMetadata holds the metadata set by the handler of a call.
+type callMetadata struct {
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+	// sent is set once the metadata was attached to the response.
+	sent bool
+}
+
+func setMetadata(ctx context.Context, md metadata.MD, field func(*callMetadata) *metadata.MD) error {
+	m, ok := ctx.Value(callMetadataKey).(*callMetadata)
+	if !ok {
+		return status.Error(codes.Internal, "failed to set metadata: not the context of a call")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sent {
+		return status.Error(codes.Internal, "failed to set metadata: the response was already sent")
+	}
+	*field(m) = metadata.Join(*field(m), md)
+	return nil
+}
+
+// attachMetadata attaches the metadata set by the handler of the call handled with ctx to its
+// response. Metadata set afterwards is rejected.
+func attachMetadata(ctx context.Context, response *api.RPCResponse) {
+	m, ok := ctx.Value(callMetadataKey).(*callMetadata)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = true
+	response.Header = m.header
+	response.Trailer = m.trailer
+}
@@ -0,0 +1,129 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/internal/cachetest"
+	"github.com/epk/mmap-rpc/pkg/client"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/metadata"
+	"github.com/epk/mmap-rpc/pkg/server"
+	"github.com/epk/mmap-rpc/pkg/status"
+)
+
+// echoMetadata sends the request ID of the client back in the header, and a trailer.
+func echoMetadata(ctx context.Context) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Internal, "no incoming metadata")
+	}
+	if err := server.SetHeader(ctx, metadata.MD{"request-id": md.Get("Request-ID")}); err != nil {
+		return err
+	}
+	return server.SetTrailer(ctx, metadata.Pairs("status", "done"))
+}
+
+// TestMetadata checks that the metadata of the client reaches handlers, and that the header and
+// trailer they set reach the client, for unary calls that succeed or fail and for streams.
+func TestMetadata(t *testing.T) {
+	srv := &server.Server{}
+	srv.RegisterHandler("/test.Test/Unary", func(ctx context.Context, data []byte) ([]byte, error) {
+		if err := echoMetadata(ctx); err != nil {
+			return nil, err
+		}
+		return proto.Marshal(&cache.GetResponse{})
+	})
+	srv.RegisterHandler("/test.Test/Fail", func(ctx context.Context, data []byte) ([]byte, error) {
+		if err := echoMetadata(ctx); err != nil {
+			return nil, err
+		}
+		return nil, status.Error(codes.NotFound, "no such key")
+	})
+	srv.RegisterStream(&server.StreamDesc{
+		MethodName:    "/test.Test/Stream",
+		ServerStreams: true,
+		Handler: func(ctx context.Context, data []byte, stream *server.ServerStream) error {
+			if err := echoMetadata(ctx); err != nil {
+				return err
+			}
+			return stream.Send(&cache.WatchEvent{Key: "key"})
+		},
+	})
+	c := cachetest.Dial(t, cachetest.Serve(t, srv))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "Request-ID", "42")
+
+	check := func(t *testing.T, header, trailer metadata.MD) {
+		t.Helper()
+		if got := string(header.Get("request-id")); got != "42" {
+			t.Errorf("got request ID %q in the header, want %q", got, "42")
+		}
+		if got := string(trailer.Get("status")); got != "done" {
+			t.Errorf("got status %q in the trailer, want %q", got, "done")
+		}
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		var header, trailer metadata.MD
+		err := c.Invoke(ctx, "/test.Test/Unary", &cache.GetRequest{}, &cache.GetResponse{}, client.Header(&header), client.Trailer(&trailer))
+		if err != nil {
+			t.Fatalf("Invoke: %v", err)
+		}
+		check(t, header, trailer)
+	})
+
+	t.Run("failing", func(t *testing.T) {
+		var header, trailer metadata.MD
+		err := c.Invoke(ctx, "/test.Test/Fail", &cache.GetRequest{}, &cache.GetResponse{}, client.Header(&header), client.Trailer(&trailer))
+		if code := status.Code(err); code != codes.NotFound {
+			t.Fatalf("got %v, want code NotFound", err)
+		}
+		check(t, header, trailer)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var header, trailer metadata.MD
+		stream, err := c.NewServerStream(ctx, "/test.Test/Stream", &cache.WatchRequest{}, client.Header(&header), client.Trailer(&trailer))
+		if err != nil {
+			t.Fatalf("failed to open stream: %v", err)
+		}
+		if err := stream.Recv(&cache.WatchEvent{}); err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if err := stream.Recv(&cache.WatchEvent{}); !errors.Is(err, io.EOF) {
+			t.Fatalf("got %v, want io.EOF", err)
+		}
+		check(t, header, trailer)
+	})
+}
+
+// TestSetMetadataAfterResponse checks that metadata set once the response was sent is rejected
+// rather than silently dropped.
+func TestSetMetadataAfterResponse(t *testing.T) {
+	srv := &server.Server{}
+	calls := make(chan context.Context, 1)
+	srv.RegisterHandler("/test.Test/Unary", func(ctx context.Context, data []byte) ([]byte, error) {
+		calls <- ctx
+		return proto.Marshal(&cache.GetResponse{})
+	})
+	c := cachetest.Dial(t, cachetest.Serve(t, srv))
+
+	if err := c.Invoke(context.Background(), "/test.Test/Unary", &cache.GetRequest{}, &cache.GetResponse{}); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	ctx := <-calls
+	if err := server.SetHeader(ctx, metadata.Pairs("key", "value")); status.Code(err) != codes.Internal {
+		t.Errorf("SetHeader after the response: got %v, want code Internal", err)
+	}
+	if err := server.SetTrailer(ctx, metadata.Pairs("key", "value")); status.Code(err) != codes.Internal {
+		t.Errorf("SetTrailer after the response: got %v, want code Internal", err)
+	}
+	if err := server.SetHeader(context.Background(), metadata.Pairs("key", "value")); status.Code(err) != codes.Internal {
+		t.Errorf("SetHeader outside of a call: got %v, want code Internal", err)
+	}
+}
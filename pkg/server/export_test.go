@@ -0,0 +1,15 @@
+package server
+
+import "context"
+
+// GuardFault exposes guardFault, to test it from a goroutine that did not enable panics on faults.
+var GuardFault = guardFault
+
+// CopyResponse exposes copyResponse, to benchmark the path of a response to the memory-mapped file.
+var CopyResponse = copyResponse
+
+// WithResponseBuffer returns a copy of ctx in which ResponseBuffer returns buf, as it does for
+// the calls whose client reserved an area for the response.
+func WithResponseBuffer(ctx context.Context, buf []byte) context.Context {
+	return context.WithValue(ctx, responseBufferKey, buf[:0])
+}
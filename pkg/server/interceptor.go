@@ -0,0 +1,28 @@
+package server
+
+import "context"
+
+// UnaryServerInfo describes the call seen by a UnaryServerInterceptor.
+type UnaryServerInfo struct {
+	// FullMethod is the fully qualified name of the method, e.g. "/cache.Cache/Get".
+	FullMethod string
+	// ConnectionID is the ID of the mmap-rpc connection the call was made on.
+	ConnectionID string
+}
+
+// UnaryServerInterceptor intercepts the execution of a call on the server. data is the serialized
+// request, and the interceptor calls handler to run the method or returns early to reject the call.
+// Errors returned by an interceptor are reported to the client like errors returned by handlers.
+type UnaryServerInterceptor func(ctx context.Context, data []byte, info *UnaryServerInfo, handler HandlerFunc) ([]byte, error)
+
+// intercept wraps handler with the interceptors of the server, the first interceptor being the
+// outermost one.
+func (s *Server) intercept(handler HandlerFunc, info *UnaryServerInfo) HandlerFunc {
+	for i := len(s.UnaryInterceptors) - 1; i >= 0; i-- {
+		interceptor, next := s.UnaryInterceptors[i], handler
+		handler = func(ctx context.Context, data []byte) ([]byte, error) {
+			return interceptor(ctx, data, info, next)
+		}
+	}
+	return handler
+}
@@ -0,0 +1,80 @@
+package server_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/internal/cachetest"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/server"
+	"github.com/epk/mmap-rpc/pkg/status"
+)
+
+// TestUnaryInterceptors checks that the first interceptor is the outermost, that interceptors see
+// the method and connection of the call, and that one can reject a call before the handler runs.
+func TestUnaryInterceptors(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		steps []string
+		infos []server.UnaryServerInfo
+	)
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, step)
+	}
+	interceptor := func(name string) server.UnaryServerInterceptor {
+		return func(ctx context.Context, data []byte, info *server.UnaryServerInfo, handler server.HandlerFunc) ([]byte, error) {
+			record(name + " before")
+			mu.Lock()
+			infos = append(infos, *info)
+			mu.Unlock()
+			out, err := handler(ctx, data)
+			record(name + " after")
+			return out, err
+		}
+	}
+	// reject denies the calls for the key "secret" without running the handler.
+	reject := func(ctx context.Context, data []byte, info *server.UnaryServerInfo, handler server.HandlerFunc) ([]byte, error) {
+		req := &cache.GetRequest{}
+		if err := proto.Unmarshal(data, req); err == nil && req.Key == "secret" {
+			return nil, status.Error(codes.PermissionDenied, "access denied")
+		}
+		return handler(ctx, data)
+	}
+
+	srv := &server.Server{UnaryInterceptors: []server.UnaryServerInterceptor{interceptor("first"), interceptor("second"), reject}}
+	srv.RegisterHandler("/test.Test/Get", func(ctx context.Context, data []byte) ([]byte, error) {
+		record("handler")
+		return proto.Marshal(&cache.GetResponse{Found: true})
+	})
+	c := cachetest.Dial(t, cachetest.Serve(t, srv))
+
+	if err := c.Invoke(context.Background(), "/test.Test/Get", &cache.GetRequest{Key: "key"}, &cache.GetResponse{}); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	want := []string{"first before", "second before", "handler", "second after", "first after"}
+	if !slices.Equal(steps, want) {
+		t.Errorf("got steps %q, want %q", steps, want)
+	}
+	wantInfo := server.UnaryServerInfo{FullMethod: "/test.Test/Get", ConnectionID: c.ConnectionID()}
+	for _, info := range infos {
+		if info != wantInfo {
+			t.Errorf("got info %+v, want %+v", info, wantInfo)
+		}
+	}
+
+	steps = nil
+	err := c.Invoke(context.Background(), "/test.Test/Get", &cache.GetRequest{Key: "secret"}, &cache.GetResponse{})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("got %v, want code PermissionDenied", err)
+	}
+	if slices.Contains(steps, "handler") {
+		t.Error("the handler ran for a rejected call")
+	}
+}
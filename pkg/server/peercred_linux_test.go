@@ -0,0 +1,71 @@
+package server_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/internal/cachetest"
+	"github.com/epk/mmap-rpc/pkg/client"
+	"github.com/epk/mmap-rpc/pkg/server"
+)
+
+// TestPeerCredentials checks that AllowedUIDs and AllowedGIDs accept or reject the test process,
+// and that handlers see its credentials.
+func TestPeerCredentials(t *testing.T) {
+	uid, gid := uint32(os.Geteuid()), uint32(os.Getegid())
+
+	tests := []struct {
+		name string
+		uids []uint32
+		gids []uint32
+		ok   bool
+	}{
+		{name: "no lists", ok: true},
+		{name: "uid listed", uids: []uint32{uid + 1, uid}, ok: true},
+		{name: "gid listed", uids: []uint32{uid + 1}, gids: []uint32{gid}, ok: true},
+		{name: "uid not listed", uids: []uint32{uid + 1}},
+		{name: "gid not listed", gids: []uint32{gid + 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &server.Server{AllowedUIDs: tt.uids, AllowedGIDs: tt.gids}
+			peers := make(chan *server.Peer, 1)
+			srv.RegisterHandler("/test.Test/Peer", func(ctx context.Context, data []byte) ([]byte, error) {
+				peer, _ := server.PeerFromContext(ctx)
+				peers <- peer
+				return proto.Marshal(&cache.GetResponse{})
+			})
+			socketPath := cachetest.Serve(t, srv)
+
+			if !tt.ok {
+				c, err := client.NewClient(socketPath)
+				if err != nil {
+					t.Fatalf("failed to create client: %v", err)
+				}
+				defer c.Close()
+				if err := c.Connect(); err == nil {
+					t.Errorf("the server accepted the connection")
+				}
+				return
+			}
+
+			c := cachetest.Dial(t, socketPath)
+			if err := c.Invoke(context.Background(), "/test.Test/Peer", &cache.GetRequest{}, &cache.GetResponse{}); err != nil {
+				t.Fatalf("Invoke: %v", err)
+			}
+			peer := <-peers
+			if peer == nil || peer.Creds == nil {
+				t.Fatalf("got peer %+v, want the credentials of the test process", peer)
+			}
+			want := server.Credentials{PID: int32(os.Getpid()), UID: uid, GID: gid}
+			if *peer.Creds != want {
+				t.Errorf("got credentials %+v, want %+v", *peer.Creds, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package server
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCredentials is only supported on Linux.
+func peerCredentials(conn net.Conn) (*Credentials, error) {
+	return nil, errors.New("peer credentials are not supported on this platform")
+}
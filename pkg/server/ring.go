@@ -0,0 +1,166 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/netstringconn"
+	"github.com/epk/mmap-rpc/pkg/ring"
+)
+
+// ringArea is the part of the region taken by the rings, the request ring followed by the
+// response ring.
+const ringArea = 2 * ring.Size
+
+// startRings sets up the rings at the start of the region of conn and starts consuming requests.
+func (s *Server) startRings(w *netstringconn.NetstringConn, conn *Connection) {
+	mmap := conn.region.Bytes()
+	conn.requests = ring.NewConsumer(mmap[:ring.Size])
+	conn.responses = ring.NewProducer(mmap[ring.Size:ringArea])
+	conn.wake = make(chan struct{}, 1)
+
+	// The region stays mapped until the consumer stopped.
+	conn.calls.Add(1)
+	go s.consumeRequests(w, conn)
+}
+
+// consumeRequests starts the RPCs queued by the client in the request ring of conn until the
+// connection is closed. A corrupted ring closes the socket, which disconnects the client.
+func (s *Server) consumeRequests(w *netstringconn.NetstringConn, conn *Connection) {
+	defer conn.calls.Done()
+
+	if err := s.popRequests(w, conn); err != nil {
+		log.Printf("[Connection ID: %s] %v\n", conn.id, err)
+		w.Close()
+	}
+}
+
+// popRequests handles the requests queued in the request ring of conn until the connection is
+// closed, or until the ring turns out to be corrupted.
+func (s *Server) popRequests(w *netstringconn.NetstringConn, conn *Connection) (err error) {
+	// The client may truncate a file-backed region under the consumer.
+	defer guardFault(debug.SetPanicOnFault(true), &err, "failed to read request ring")
+
+	for {
+		msg, err := conn.requests.Pop()
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			if !conn.requests.Spin() && conn.requests.Park() && !conn.waitForRequests() {
+				return nil
+			}
+			continue
+		}
+
+		if err := s.handleRingRequest(w, conn, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handleRingRequest handles a message of the request ring of conn. The ring carries RPCRequests
+// and the CancelRequests for the calls sent through it, which therefore cannot overtake them. An
+// error means that the ring is corrupted.
+func (s *Server) handleRingRequest(w *netstringconn.NetstringConn, conn *Connection, msg []byte) error {
+	request := &anypb.Any{}
+	if err := proto.Unmarshal(msg, request); err != nil {
+		return fmt.Errorf("failed to unmarshal ring request: %w", err)
+	}
+
+	switch request.TypeUrl {
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.RPCRequest{})):
+		typedRequest := &api.RPCRequest{}
+		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal data request: %w", err)
+		}
+		if err := s.startCall(conn.ctx, w, typedRequest); err != nil {
+			log.Printf("[Connection ID: %s] %v\n", conn.id, err)
+		}
+		return nil
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.CancelRequest{})):
+		typedRequest := &api.CancelRequest{}
+		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal cancel request: %w", err)
+		}
+		s.handleCancel(w, typedRequest)
+		return nil
+	default:
+		return fmt.Errorf("unknown ring request typeUrl: %s", request.TypeUrl)
+	}
+}
+
+// waitForRequests blocks the parked consumer of requests of c until it is woken up. It returns
+// false if the connection was closed instead.
+func (c *Connection) waitForRequests() bool {
+	if c.futex {
+		// The flag is set before checking the context, handleDisconnect cancels the context
+		// before clearing the flag, so the consumer either returns or is woken up.
+		if c.ctx.Err() != nil {
+			return false
+		}
+		c.requests.Sleep()
+		return true
+	}
+
+	select {
+	case <-c.wake:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// wakeup resumes the consumer of requests of c if it is parked.
+func (c *Connection) wakeup() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sendRPCResponse sends the response of an RPC, through the response ring of its connection if it
+// has one and the ring has room for it, and otherwise over the socket.
+func (s *Server) sendRPCResponse(w *netstringconn.NetstringConn, response *api.RPCResponse) error {
+	conn, ok := s.connection(w, response.ConnectionId)
+	if !ok || conn.responses == nil || !conn.begin() {
+		return s.send(w, response)
+	}
+	// A fault writing to a truncated region leaves the response to the socket.
+	pushed, wake, _ := pushResponse(conn, response)
+	if pushed && wake && conn.futex {
+		conn.responses.Wake()
+		wake = false
+	}
+	conn.calls.Done()
+
+	if !pushed {
+		return s.send(w, response)
+	}
+	if wake {
+		return s.send(w, &api.Wakeup{ConnectionId: conn.id})
+	}
+	return nil
+}
+
+// pushResponse queues response in the response ring of conn. wake reports whether the client
+// must be woken up.
+func pushResponse(conn *Connection, response *api.RPCResponse) (pushed, wake bool, err error) {
+	data, err := proto.Marshal(response)
+	if err != nil {
+		return false, false, err
+	}
+
+	conn.responsesMu.Lock()
+	defer conn.responsesMu.Unlock()
+
+	defer guardFault(debug.SetPanicOnFault(true), &err, "failed to write response ring")
+
+	pushed, wake = conn.responses.Push(data)
+	return pushed, wake, nil
+}
@@ -5,42 +5,133 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"slices"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/tysonmote/gommap"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/metadata"
 	"github.com/epk/mmap-rpc/pkg/netstringconn"
+	"github.com/epk/mmap-rpc/pkg/region"
+	"github.com/epk/mmap-rpc/pkg/ring"
+	"github.com/epk/mmap-rpc/pkg/status"
 )
 
 type HandlerFunc func(ctx context.Context, data []byte) ([]byte, error)
 
 type Connection struct {
-	id       string
-	mmapFile *os.File
-	mmap     gommap.MMap
+	id     string
+	region *region.Region
+	// socket is the socket the connection was created on, the connection is closed along with it.
+	socket *netstringconn.NetstringConn
+	// memfd is set when the region is a memfd passed to the client instead of a file on disk.
+	memfd bool
+	// requests and responses are the rings at the start of the region of connections that
+	// exchange RPCs through shared memory, nil otherwise. responsesMu serializes the calls
+	// pushing their responses, and wake is signaled when the client queued requests while
+	// the consumer of requests was parked.
+	requests    *ring.Ring
+	responses   *ring.Ring
+	responsesMu sync.Mutex
+	wake        chan struct{}
+	// futex is set when parked ring consumers sleep on a futex instead of waiting for Wakeup messages.
+	futex bool
+	// ctx is the parent of the contexts passed to handlers, it is canceled on disconnect.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// pending holds responses that did not fit in the area reserved by the client,
+	// keyed by request ID, until the client fetches them.
+	pending sync.Map
+	// cancels holds the context.CancelFunc of the calls in flight, keyed by request ID.
+	cancels sync.Map
+	// canceled holds the request IDs of the calls in flight that the client canceled, so that
+	// their responses are not held for a FetchRequest that never comes.
+	canceled sync.Map
+	// streams holds the *ServerStream of the streaming calls in flight, keyed by request ID.
+	streams sync.Map
+
+	// mu guards closed, calls must not be added to once the connection is closed.
+	mu     sync.Mutex
+	closed bool
+	// calls counts the calls using the region, which is unmapped once they are done.
+	calls sync.WaitGroup
 }
 
 type Server struct {
-	listener       net.Listener
-	connections    sync.Map
-	mmapFilePrefix string
+	// MmapSize is the initial size of the mmap file for clients that do not request one.
+	// Defaults to DefaultMmapSize.
+	MmapSize int64
+	// MaxMmapSize is the size an mmap file may grow to. Requests and responses that do not
+	// fit fail with ErrPayloadTooLarge. Defaults to DefaultMaxMmapSize.
+	MaxMmapSize int64
+	// UnaryInterceptors are run around every handler, in order, the first one being the outermost.
+	UnaryInterceptors []UnaryServerInterceptor
+	// MmapFilePrefix is prepended to the name of the mmap files, usually a directory followed by
+	// a path separator. It is set by ListenAndServe. The directory must be owned by the user the
+	// server runs as and not be writable by its group or other users. Defaults to a private
+	// directory created in os.TempDir and removed on Close.
+	MmapFilePrefix string
+	// MmapFileMode are the permissions of the mmap files. Defaults to DefaultMmapFileMode, which
+	// only lets clients running as the same user as the server open them, see ChownToPeer.
+	MmapFileMode os.FileMode
+	// ChownToPeer makes the connecting process the owner of its mmap file, so that clients
+	// running as other users can open it. The server usually needs CAP_CHOWN for this.
+	ChownToPeer bool
+	// AllowedUIDs and AllowedGIDs restrict which processes may connect, based on the credentials
+	// of the peer. A process is accepted if its UID or GID is listed. Both empty accepts any
+	// process that can open the socket. Sockets whose peer credentials cannot be read are
+	// rejected when a list is set.
+	AllowedUIDs []uint32
+	AllowedGIDs []uint32
+
+	listener    net.Listener
+	connections sync.Map
+	// tempDir is the directory created for the mmap files when MmapFilePrefix is not set.
+	tempDir string
+	// sockets holds the *netstringconn.NetstringConn of the connected clients.
+	sockets sync.Map
+	// serving counts the goroutines serving sockets, which tear down the connections of their
+	// socket when they exit.
+	serving sync.WaitGroup
+
+	// mu guards listener, tempDir and shuttingDown, calls must not be added to once the server is shutting down.
+	mu           sync.Mutex
+	shuttingDown bool
+	// calls counts the RPCs in flight across all connections.
+	calls sync.WaitGroup
 
 	implsStubs sync.Map
 }
 
-var mmapFileSize int64 = 1 * 1024 * 1024 // 1MB
+const (
+	DefaultMmapSize    int64 = 1 * 1024 * 1024  // 1MB
+	DefaultMaxMmapSize int64 = 64 * 1024 * 1024 // 64MB
+
+	DefaultMmapFileMode os.FileMode = 0o600
+)
 
+// ErrPayloadTooLarge is reported when a request or response does not fit in MaxMmapSize.
+var ErrPayloadTooLarge = region.ErrPayloadTooLarge
+
+// ErrServerClosed is returned by Serve and ListenAndServe after a call to Close or Shutdown.
+var ErrServerClosed = errors.New("server closed")
+
+// ListenAndServe listens on the Unix socket at socketPath, removing an existing file, and serves
+// clients with Serve. The mmap files are named with mmapFilePrefix.
 func (s *Server) ListenAndServe(socketPath, mmapFilePrefix string) error {
-	s.mmapFilePrefix = mmapFilePrefix
+	s.MmapFilePrefix = mmapFilePrefix
 
 	if err := os.RemoveAll(socketPath); err != nil {
 		return fmt.Errorf("failed to remove existing socket: %w", err)
@@ -50,20 +141,197 @@ func (s *Server) ListenAndServe(socketPath, mmapFilePrefix string) error {
 	if err != nil {
 		return fmt.Errorf("failed to listen on socket: %w", err)
 	}
-	s.listener = listener
+
+	return s.Serve(listener)
+}
+
+// Serve accepts clients on listener, which must be a Unix socket listener, until the server is
+// closed. It always returns a non-nil error and closes listener, ErrServerClosed after a call to
+// Close or Shutdown.
+func (s *Server) Serve(listener net.Listener) error {
 	defer listener.Close()
 
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	if err := s.prepareMmapDir(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	var backoff time.Duration
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			continue
+			if s.closing() {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() {
+				// Out of file descriptors and the like, try again later.
+				backoff = min(max(2*backoff, minAcceptBackoff), maxAcceptBackoff)
+				log.Printf("Error accepting connection: %v; retrying in %v\n", err, backoff)
+				time.Sleep(backoff)
+				continue
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
 		}
+		backoff = 0
 
-		go s.handleConnection(conn)
+		s.mu.Lock()
+		if s.shuttingDown {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.serving.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.serving.Done()
+			s.handleConnection(conn)
+		}()
+	}
+}
+
+// Bounds of the delay between attempts to accept after a temporary error.
+const (
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+)
+
+// prepareMmapDir creates a private directory for the mmap files if no prefix is set, and otherwise
+// checks that other users cannot tamper with the files in the directory of the prefix: it must be
+// owned by the user the server runs as and not be writable by its group or other users.
+func (s *Server) prepareMmapDir() error {
+	if s.MmapFilePrefix == "" {
+		dir, err := os.MkdirTemp("", "mmap-rpc-")
+		if err != nil {
+			return fmt.Errorf("failed to create mmap directory: %w", err)
+		}
+		s.tempDir = dir
+		s.MmapFilePrefix = dir + string(filepath.Separator)
+		return nil
+	}
+
+	dir := filepath.Dir(s.MmapFilePrefix + "x")
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat mmap directory: %w", err)
 	}
+	if !info.IsDir() {
+		return fmt.Errorf("mmap directory %s is not a directory", dir)
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("mmap directory %s is writable by other users", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("mmap directory %s is owned by uid %d, not by the server's uid %d", dir, stat.Uid, os.Geteuid())
+	}
+	return nil
 }
 
+// closing reports whether Close or Shutdown was called.
+func (s *Server) closing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.shuttingDown
+}
+
+// Close stops the server immediately. Calls in flight are canceled, and the mmap files are
+// unmapped and removed once their handlers return.
 func (s *Server) Close() {
+	s.stopListening()
+	s.closeConnections()
+}
+
+// Shutdown stops the server gracefully. It stops accepting sockets, sends a GoAway message to the
+// connected clients and rejects new calls, then waits for the calls in flight to complete before
+// unmapping and removing the mmap files and closing the sockets.
+//
+// If ctx is done before the calls completed, Shutdown returns ctx.Err() and the remaining calls
+// are canceled, their mmap files are released in the background once the handlers return.
+// Shutdown returns nil if draining completed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopListening()
+
+	s.sockets.Range(func(key, _ any) bool {
+		if err := s.send(key.(*netstringconn.NetstringConn), &api.GoAway{}); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Printf("failed to send go away: %v\n", err)
+		}
+		return true
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		s.calls.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		s.closeConnections()
+		return nil
+	case <-ctx.Done():
+		go s.closeConnections()
+		return ctx.Err()
+	}
+}
+
+// allowed reports whether peer passes AllowedUIDs and AllowedGIDs.
+func (s *Server) allowed(peer *Peer) bool {
+	if len(s.AllowedUIDs) == 0 && len(s.AllowedGIDs) == 0 {
+		return true
+	}
+	if peer.Creds == nil {
+		return false
+	}
+	return slices.Contains(s.AllowedUIDs, peer.Creds.UID) || slices.Contains(s.AllowedGIDs, peer.Creds.GID)
+}
+
+// stopListening marks the server as shutting down and closes its listener.
+func (s *Server) stopListening() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shuttingDown = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+}
+
+// addSocket registers a connected socket, so that it is closed along with the server. It fails once
+// the server is closing, since the sockets may already have been closed.
+func (s *Server) addSocket(socket *netstringconn.NetstringConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shuttingDown {
+		return false
+	}
+	s.sockets.Store(socket, struct{}{})
+	return true
+}
+
+// disconnectSocket disconnects the connections created on socket.
+func (s *Server) disconnectSocket(socket *netstringconn.NetstringConn) {
+	s.connections.Range(
+		func(key, value interface{}) bool {
+			if conn := value.(*Connection); conn.socket == socket {
+				s.handleDisconnect(conn.id)
+			}
+			return true
+		},
+	)
+}
+
+// closeConnections disconnects every connection and closes the sockets of the clients.
+func (s *Server) closeConnections() {
 	s.connections.Range(
 		func(key, value interface{}) bool {
 			conn := value.(*Connection)
@@ -72,16 +340,52 @@ func (s *Server) Close() {
 		},
 	)
 
-	s.listener.Close()
+	s.sockets.Range(func(key, _ any) bool {
+		key.(*netstringconn.NetstringConn).Close()
+		return true
+	})
+	// Disconnect requests may still be tearing down connections, remove their files first.
+	s.serving.Wait()
+
+	s.mu.Lock()
+	tempDir := s.tempDir
+	s.mu.Unlock()
+	if tempDir != "" {
+		if err := os.Remove(tempDir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("failed to remove mmap directory: %v\n", err)
+		}
+	}
 }
 
 func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
+	peer := &Peer{Addr: conn.RemoteAddr()}
+	creds, err := peerCredentials(conn)
+	if err != nil {
+		log.Printf("Failed to read peer credentials: %v\n", err)
+	} else {
+		peer.Creds = creds
+	}
+	if !s.allowed(peer) {
+		log.Printf("Rejected connection from peer %+v\n", peer.Creds)
+		return
+	}
+
+	// Calls made over this socket are canceled once it is closed.
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), peerKey, peer))
+	defer cancel()
+
 	nsConn := netstringconn.NewNetstringConn(conn)
+	if !s.addSocket(nsConn) {
+		return
+	}
+	defer s.sockets.Delete(nsConn)
+	// Clients that go away without disconnecting leave their connections behind.
+	defer s.disconnectSocket(nsConn)
 
 	for {
-		if err := s.receiveAndSend(nsConn); err != nil {
+		if err := s.receiveAndSend(ctx, nsConn); err != nil {
 			if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
 				// Connection closed or EOF reached, exit gracefully
 				return
@@ -96,7 +400,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 	}
 }
 
-func (s *Server) receiveAndSend(w *netstringconn.NetstringConn) error {
+func (s *Server) receiveAndSend(ctx context.Context, w *netstringconn.NetstringConn) error {
 	msg, err := w.Read()
 	if err != nil {
 		return fmt.Errorf("failed to read request: %w", err)
@@ -111,27 +415,128 @@ func (s *Server) receiveAndSend(w *netstringconn.NetstringConn) error {
 
 	switch request.TypeUrl {
 	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.ConnectRequest{})):
-		response = s.handleConnect()
+		typedRequest := &api.ConnectRequest{}
+		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal connect request: %w", err)
+		}
+		connectResponse := s.handleConnect(ctx, w, typedRequest)
+		if conn, ok := s.connection(w, connectResponse.ConnectionId); ok && conn.memfd {
+			// The client maps the memfd passed along with the response.
+			return s.sendFD(w, connectResponse, int(conn.region.Fd()))
+		}
+		response = connectResponse
 	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.DisconnectRequest{})):
 		typedRequest := &api.DisconnectRequest{}
 		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
 			return fmt.Errorf("failed to unmarshal disconnect request: %w", err)
 		}
-		s.handleDisconnect(typedRequest.GetConnectionId())
+		if _, ok := s.connection(w, typedRequest.GetConnectionId()); ok {
+			s.handleDisconnect(typedRequest.GetConnectionId())
+		}
 		response = &api.Empty{}
 	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.RPCRequest{})):
 		typedRequest := &api.RPCRequest{}
 		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
 			return fmt.Errorf("failed to unmarshal data request: %w", err)
 		}
-		response = s.handleData(typedRequest)
+		return s.startCall(ctx, w, typedRequest)
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.Wakeup{})):
+		typedRequest := &api.Wakeup{}
+		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal wakeup: %w", err)
+		}
+		if conn, ok := s.connection(w, typedRequest.ConnectionId); ok && conn.requests != nil {
+			conn.wakeup()
+		}
+		return nil
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.CancelRequest{})):
+		typedRequest := &api.CancelRequest{}
+		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal cancel request: %w", err)
+		}
+		s.handleCancel(w, typedRequest)
+		return nil
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.StreamFrame{})):
+		typedRequest := &api.StreamFrame{}
+		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal stream frame: %w", err)
+		}
+		s.handleStreamFrame(w, typedRequest)
+		return nil
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.StreamAck{})):
+		typedRequest := &api.StreamAck{}
+		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal stream ack: %w", err)
+		}
+		s.handleStreamAck(w, typedRequest)
+		return nil
+	case "type.googleapis.com" + "/" + string(proto.MessageName(&api.FetchRequest{})):
+		typedRequest := &api.FetchRequest{}
+		if err := anypb.UnmarshalTo(request, typedRequest, proto.UnmarshalOptions{}); err != nil {
+			return fmt.Errorf("failed to unmarshal fetch request: %w", err)
+		}
+		var fetched bool
+		response, fetched = s.handleFetch(w, typedRequest)
+		if fetched {
+			// The call held by the pending response is complete once the response is sent.
+			defer s.calls.Done()
+		}
 	default:
 		return fmt.Errorf("unknown request typeUrl: %s", request.TypeUrl)
 	}
 
-	responseData, err := proto.Marshal(response)
+	return s.send(w, response)
+}
+
+// startCall runs the RPC req in its own goroutine. RPCs run concurrently, responses are matched to
+// requests by the client using the request ID.
+func (s *Server) startCall(ctx context.Context, w *netstringconn.NetstringConn, req *api.RPCRequest) error {
+	if !s.beginCall() {
+		return s.sendRPCResponse(w, fail(req.ConnectionId, &api.RPCResponse{
+			ConnectionId:             req.ConnectionId,
+			FullyQualifiedMethodName: req.FullyQualifiedMethodName,
+			RequestId:                req.RequestId,
+		}, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.New(codes.Unavailable, "server is shutting down")))
+	}
+	// The context is set up before the call starts so that a CancelRequest read after the request
+	// finds it, and so is a stream, for the frames sent by the client. The client sends the
+	// CancelRequest the same way as the request, over the socket or through the request ring.
+	ctx, cancel := s.callContext(ctx, w, req)
+	var stream *ServerStream
+	var trailer *api.RPCResponse
+	if req.Stream {
+		stream, trailer = s.openStream(ctx, w, req)
+	}
+	go func() {
+		defer s.calls.Done()
+		defer cancel()
+		// The client may truncate a file-backed region under the handler, see isFault.
+		debug.SetPanicOnFault(true)
+		var err error
+		if req.Stream {
+			if stream != nil {
+				trailer = s.handleStream(stream)
+			}
+			attachMetadata(ctx, trailer)
+			// The trailer must not overtake the stream frames, which are sent over the socket.
+			err = s.send(w, trailer)
+		} else {
+			response := s.handleData(ctx, w, req)
+			attachMetadata(ctx, response)
+			err = s.sendRPCResponse(w, response)
+		}
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Printf("[Connection ID: %s] %v\n", req.ConnectionId, err)
+		}
+	}()
+	return nil
+}
+
+// send converts the message to anypb and sends it to the client.
+func (s *Server) send(w *netstringconn.NetstringConn, msg proto.Message) error {
+	responseData, err := marshalAny(msg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal response: %w", err)
+		return err
 	}
 
 	if err := w.Write(responseData); err != nil {
@@ -141,104 +546,532 @@ func (s *Server) receiveAndSend(w *netstringconn.NetstringConn) error {
 	return nil
 }
 
-func (s *Server) handleConnect() *api.ConnectResponse {
-	connID := uuid.New().String()
-	mmapFilename := filepath.Join(s.mmapFilePrefix + connID + ".mmap")
+// sendFD is like send, but passes the file descriptor fd along with the message.
+func (s *Server) sendFD(w *netstringconn.NetstringConn, msg proto.Message, fd int) error {
+	responseData, err := marshalAny(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteFD(responseData, fd); err != nil {
+		return fmt.Errorf("failed to write response: %w", err)
+	}
 
-	file, err := os.Create(mmapFilename)
+	return nil
+}
+
+func marshalAny(msg proto.Message) ([]byte, error) {
+	any, err := anypb.New(msg)
 	if err != nil {
-		log.Printf("[Connection ID: %s] failed to create mmap file: %v\n", connID, err)
-		return &api.ConnectResponse{Error: err.Error()}
+		return nil, fmt.Errorf("failed to create any: %w", err)
 	}
 
-	if err := file.Truncate(mmapFileSize); err != nil {
-		log.Printf("[Connection ID: %s] failed to truncate mmap file: %v\n", connID, err)
-		file.Close()
-		return &api.ConnectResponse{Error: err.Error()}
+	data, err := proto.Marshal(any)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
 	}
+	return data, nil
+}
 
-	mmap, err := gommap.Map(file.Fd(), gommap.PROT_READ|gommap.PROT_WRITE, gommap.MAP_SHARED)
+func (s *Server) handleConnect(ctx context.Context, w *netstringconn.NetstringConn, req *api.ConnectRequest) *api.ConnectResponse {
+	connID := uuid.New().String()
+	mmapFilename := filepath.Join(s.MmapFilePrefix + connID + ".mmap")
+
+	mmapSize := int64(req.MmapSize)
+	if mmapSize == 0 {
+		mmapSize = s.mmapSize()
+	}
+	mmapSize = min(mmapSize, s.maxMmapSize())
+	// The rings take the start of the region, the client needs room for its calls past them.
+	useRing := req.Ring && s.maxMmapSize() >= 2*ringArea
+	if useRing {
+		mmapSize = max(mmapSize, 2*ringArea)
+	}
+
+	// Clients may ask for a memfd, which falls back to a file on platforms without memfd_create.
+	var r *region.Region
+	var err error
+	memfd := false
+	if req.Memfd {
+		// Memfds are sealed and cannot grow, they are sized for the largest payloads upfront.
+		r, err = region.CreateMemfd(connID, s.maxMmapSize())
+		memfd = err == nil
+		if errors.Is(err, region.ErrMemfdUnsupported) {
+			err = nil
+		}
+	}
+	if r == nil && err == nil {
+		r, err = s.createFile(ctx, mmapFilename, mmapSize)
+	}
 	if err != nil {
-		log.Printf("[Connection ID: %s] failed to mmap file: %v\n", connID, err)
-		file.Close()
+		log.Printf("[Connection ID: %s] %v\n", connID, err)
 		return &api.ConnectResponse{Error: err.Error()}
 	}
 
+	r.SetMaxSize(s.maxMmapSize())
+
 	conn := &Connection{
-		id:       connID,
-		mmapFile: file,
-		mmap:     mmap,
+		id:     connID,
+		region: r,
+		socket: w,
+		memfd:  memfd,
+	}
+	conn.ctx, conn.cancel = context.WithCancel(context.WithValue(ctx, connectionIDKey, connID))
+
+	if useRing {
+		conn.futex = req.Futex && ring.FutexSupported
+		s.startRings(w, conn)
 	}
 
 	s.connections.Store(connID, conn)
 
-	return &api.ConnectResponse{
+	response := &api.ConnectResponse{
 		ConnectionId: connID,
-		MmapFilename: mmapFilename,
+		MmapSize:     uint64(r.Len()),
+		MaxMmapSize:  uint64(r.MaxSize()),
+		Memfd:        memfd,
+		Ring:         useRing,
+		Futex:        conn.futex,
+	}
+	if !memfd {
+		response.MmapFilename = mmapFilename
+	}
+	return response
+}
+
+// createFile creates the mmap file for a connection in the mmap directory.
+func (s *Server) createFile(ctx context.Context, filename string, size int64) (*region.Region, error) {
+	r, err := region.Create(filename, size, s.mmapFileMode())
+	if err != nil {
+		return nil, err
 	}
+
+	if s.ChownToPeer {
+		if err := chownToPeer(ctx, r); err != nil {
+			r.Close()
+			os.Remove(filename)
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// chownToPeer makes the peer of ctx the owner of the region's file.
+func chownToPeer(ctx context.Context, r *region.Region) error {
+	peer, ok := PeerFromContext(ctx)
+	if !ok || peer.Creds == nil {
+		return errors.New("failed to chown mmap file: peer credentials are not available")
+	}
+	return r.Chown(int(peer.Creds.UID), int(peer.Creds.GID))
+}
+
+func (s *Server) mmapFileMode() os.FileMode {
+	if s.MmapFileMode != 0 {
+		return s.MmapFileMode
+	}
+	return DefaultMmapFileMode
+}
+
+func (s *Server) mmapSize() int64 {
+	if s.MmapSize > 0 {
+		return s.MmapSize
+	}
+	return DefaultMmapSize
+}
+
+func (s *Server) maxMmapSize() int64 {
+	if s.MaxMmapSize > 0 {
+		return s.MaxMmapSize
+	}
+	return DefaultMaxMmapSize
+}
+
+// beginCall registers an RPC in flight, it fails once the server is shutting down.
+func (s *Server) beginCall() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shuttingDown {
+		return false
+	}
+	s.calls.Add(1)
+	return true
 }
 
 func (s *Server) handleDisconnect(connID string) {
-	connInterface, ok := s.connections.Load(connID)
+	connInterface, ok := s.connections.LoadAndDelete(connID)
 	if !ok {
 		return
 	}
 	conn := connInterface.(*Connection)
 
-	if err := conn.mmapFile.Close(); err != nil {
-		log.Printf("[Connection ID: %s] failed to close mmap file: %v\n", connID, err)
+	// Handlers may still read the request from the region, it is unmapped once they returned.
+	conn.mu.Lock()
+	conn.closed = true
+	conn.mu.Unlock()
+	conn.cancel()
+	if conn.futex {
+		// The consumer of requests may sleep on its ring.
+		conn.requests.Interrupt()
+	}
+	conn.calls.Wait()
+	conn.pending.Range(func(key, _ any) bool {
+		if _, ok := conn.pending.LoadAndDelete(key); ok {
+			s.calls.Done()
+		}
+		return true
+	})
+
+	if err := conn.region.Close(); err != nil {
+		log.Printf("[Connection ID: %s] %v\n", connID, err)
 	}
 
-	if err := os.Remove(conn.mmapFile.Name()); err != nil {
+	if conn.memfd {
+		// The memfd is freed once neither side maps it anymore.
+		return
+	}
+	if err := os.Remove(conn.region.Name()); err != nil {
 		log.Printf("[Connection ID: %s] failed to remove mmap file: %v\n", connID, err)
 	}
+}
+
+// connection returns the connection with the given ID if it was created on socket w. Connection IDs
+// of other sockets are treated as unknown so that a client cannot use another client's region.
+func (s *Server) connection(w *netstringconn.NetstringConn, connID string) (*Connection, bool) {
+	connInterface, ok := s.connections.Load(connID)
+	if !ok {
+		return nil, false
+	}
+	conn := connInterface.(*Connection)
+	if conn.socket != w {
+		log.Printf("[Connection ID: %s] rejected use from another socket\n", connID)
+		return nil, false
+	}
+	return conn, true
+}
+
+// begin registers a call using the region of the connection, it fails once the connection is closed.
+func (c *Connection) begin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	s.connections.Delete(connID)
+	if c.closed {
+		return false
+	}
+	c.calls.Add(1)
+	return true
 }
 
 func (s *Server) RegisterHandler(methodName string, handler HandlerFunc) {
 	s.implsStubs.Store(methodName, handler)
 }
 
-func (s *Server) handleData(req *api.RPCRequest) *api.RPCResponse {
+// HandleRequest unmarshals data into req, calls handler with it and returns the marshaled
+// response. The generated RegisterMmapRPC<Service>Server functions use it for unary methods.
+func HandleRequest[Req, Resp proto.Message](
+	ctx context.Context,
+	data []byte,
+	handler func(context.Context, Req) (Resp, error),
+	req Req,
+) ([]byte, error) {
+	if err := proto.Unmarshal(data, req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to unmarshal request: %v", err)
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// The response is marshaled in place when it fits in the area reserved by the client.
+	out, err := proto.MarshalOptions{}.MarshalAppend(ResponseBuffer(ctx), resp)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal response: %v", err)
+	}
+	return out, nil
+}
+
+// callContext returns the context for an RPC. It is derived from the context of the connection
+// the call was made on, bounded by the timeout sent by the client and canceled by a CancelRequest
+// for the same request ID. It carries the metadata sent by the client, and collects the metadata
+// set by the handler.
+func (s *Server) callContext(ctx context.Context, w *netstringconn.NetstringConn, req *api.RPCRequest) (context.Context, context.CancelFunc) {
+	var conn *Connection
+	if c, ok := s.connection(w, req.ConnectionId); ok {
+		conn = c
+		ctx = conn.ctx
+	}
+
+	ctx = context.WithValue(ctx, methodKey, req.FullyQualifiedMethodName)
+	ctx = context.WithValue(ctx, callMetadataKey, &callMetadata{})
+	if len(req.Header) > 0 {
+		md := make(metadata.MD, len(req.Header))
+		for key, value := range req.Header {
+			md.Set(key, value)
+		}
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+
+	var cancel context.CancelFunc
+	if req.Timeout != nil {
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout.AsDuration())
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	if conn == nil {
+		return ctx, cancel
+	}
+
+	conn.cancels.Store(req.RequestId, cancel)
+	return ctx, func() {
+		conn.cancels.Delete(req.RequestId)
+		conn.canceled.Delete(req.RequestId)
+		cancel()
+	}
+}
+
+func (s *Server) handleCancel(w *netstringconn.NetstringConn, req *api.CancelRequest) {
+	conn, ok := s.connection(w, req.ConnectionId)
+	if !ok {
+		return
+	}
+
+	if cancel, ok := conn.cancels.Load(req.RequestId); ok {
+		conn.canceled.Store(req.RequestId, struct{}{})
+		cancel.(context.CancelFunc)()
+		if _, ok := conn.cancels.Load(req.RequestId); !ok {
+			// The call completed before it was marked, nothing removes the mark anymore.
+			conn.canceled.Delete(req.RequestId)
+		}
+	}
+	// The client no longer waits for a response that is pending.
+	if _, ok := conn.pending.LoadAndDelete(req.RequestId); ok {
+		s.calls.Done()
+	}
+}
+
+func (s *Server) handleData(ctx context.Context, w *netstringconn.NetstringConn, req *api.RPCRequest) *api.RPCResponse {
 	response := &api.RPCResponse{
 		ConnectionId:             req.ConnectionId,
 		FullyQualifiedMethodName: req.FullyQualifiedMethodName,
+		RequestId:                req.RequestId,
 		Size:                     0,
 	}
 
-	connInterface, ok := s.connections.Load(req.ConnectionId)
-	if !ok {
-		response.Error = fmt.Sprintf("connection not found: %s", req.ConnectionId)
-		log.Printf("[Connection ID: %s] %s\n", req.ConnectionId, response.Error)
-		return response
+	conn, ok := s.connection(w, req.ConnectionId)
+	if !ok || !conn.begin() {
+		return fail(req.ConnectionId, response, api.ErrorReason_ERROR_REASON_CONNECTION_NOT_FOUND, status.Newf(codes.FailedPrecondition, "connection not found: %s", req.ConnectionId))
 	}
-	conn := connInterface.(*Connection)
+	defer conn.calls.Done()
 
 	handlerInterface, ok := s.implsStubs.Load(req.FullyQualifiedMethodName)
 	if !ok {
-		response.Error = fmt.Sprintf("method not found: %s", req.FullyQualifiedMethodName)
-		log.Printf("[Connection ID: %s] %s\n", conn.id, response.Error)
-		return response
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_METHOD_NOT_FOUND, status.Newf(codes.Unimplemented, "method not found: %s", req.FullyQualifiedMethodName))
 	}
 
 	handler, ok := handlerInterface.(HandlerFunc)
 	if !ok {
-		response.Error = fmt.Sprintf("invalid handler for method: %s", req.FullyQualifiedMethodName)
-		log.Printf("[Connection ID: %s] %s\n", conn.id, response.Error)
-		return response
+		if _, ok := handlerInterface.(*StreamDesc); ok {
+			return fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.Newf(codes.Unimplemented, "method %s is a streaming method", req.FullyQualifiedMethodName))
+		}
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.Newf(codes.Internal, "invalid handler for method: %s", req.FullyQualifiedMethodName))
 	}
 
-	data := conn.mmap[:req.Size]
-	out, err := handler(context.Background(), data)
+	handler = s.intercept(handler, &UnaryServerInfo{
+		FullMethod:   req.FullyQualifiedMethodName,
+		ConnectionID: conn.id,
+	})
+
+	mmap, data, failed := readRequest(conn, req, response)
+	if failed != nil {
+		return failed
+	}
+	ctx = context.WithValue(ctx, rawRequestKey, data)
+	if req.ResponseOffset <= uint64(len(mmap)) && req.ResponseCapacity <= uint64(len(mmap))-req.ResponseOffset {
+		ctx = context.WithValue(ctx, responseBufferKey, mmap[req.ResponseOffset:req.ResponseOffset:req.ResponseOffset+req.ResponseCapacity])
+	}
+	out, err := callHandler(ctx, handler, data)
 	if err != nil {
-		response.Error = fmt.Sprintf("handler error: %v", err)
-		log.Printf("[Connection ID: %s] %s\n", conn.id, response.Error)
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_HANDLER, status.Convert(err))
+	}
+
+	if int64(len(out)) > conn.region.MaxSize() {
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_PAYLOAD_TOO_LARGE, payloadTooLarge(uint64(len(out)), uint64(conn.region.MaxSize()),
+			"response size %d exceeds maximum mmap size %d", len(out), conn.region.MaxSize()))
+	}
+
+	// The response is written to the area reserved by the client, past the request so that
+	// the request stays intact. Responses that do not fit are held until the client fetches them.
+	if uint64(len(out)) > req.ResponseCapacity {
+		// The call stays in flight until the response is fetched, so that Shutdown waits for it.
+		s.calls.Add(1)
+		conn.pending.Store(req.RequestId, out)
+		// A CancelRequest handled before the response was stored could not drop it. The mark is
+		// checked after storing it, so that either this call or handleCancel drops it.
+		if _, ok := conn.canceled.Load(req.RequestId); ok {
+			if _, ok := conn.pending.LoadAndDelete(req.RequestId); ok {
+				s.calls.Done()
+			}
+			return fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.New(codes.Canceled, "call canceled by the client"))
+		}
+		response.Pending = true
+		response.Size = uint64(len(out))
+		response.MmapSize = uint64(conn.region.Len())
 		return response
 	}
 
-	writeLimit := copy(conn.mmap[:len(out)], out)
+	return writeResponse(conn, response, req.ResponseOffset, out)
+}
+
+// readRequest remaps the region of conn to the size seen by the client and returns the mapping and
+// the request data in it, or the failed response if the request is not in the region.
+func readRequest(conn *Connection, req *api.RPCRequest, response *api.RPCResponse) (mmap, data []byte, failed *api.RPCResponse) {
+	// The client grows the region when the request does not fit.
+	if err := conn.region.Remap(int64(req.MmapSize)); err != nil {
+		return nil, nil, fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, remapFailed(err))
+	}
+	if err := conn.region.Validate(); err != nil {
+		return nil, nil, fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.Newf(codes.DataLoss, "%v", err))
+	}
+
+	mmap = conn.region.Bytes()
+	if req.Offset > uint64(len(mmap)) || req.Size > uint64(len(mmap))-req.Offset {
+		return nil, nil, fail(conn.id, response, api.ErrorReason_ERROR_REASON_PAYLOAD_TOO_LARGE, payloadTooLarge(req.Offset+req.Size, uint64(len(mmap)),
+			"request at offset %d with size %d exceeds mmap size %d", req.Offset, req.Size, len(mmap)))
+	}
+
+	// The capacity is limited so that appending to the request cannot overwrite the response area.
+	return mmap, mmap[req.Offset : req.Offset+req.Size : req.Offset+req.Size], nil
+}
+
+// handleFetch writes a pending response to the slot reserved by the client. It reports whether
+// the pending response was taken, completing its call.
+func (s *Server) handleFetch(w *netstringconn.NetstringConn, req *api.FetchRequest) (*api.RPCResponse, bool) {
+	response := &api.RPCResponse{
+		ConnectionId: req.ConnectionId,
+		RequestId:    req.RequestId,
+	}
+
+	conn, ok := s.connection(w, req.ConnectionId)
+	if !ok || !conn.begin() {
+		return fail(req.ConnectionId, response, api.ErrorReason_ERROR_REASON_CONNECTION_NOT_FOUND, status.Newf(codes.FailedPrecondition, "connection not found: %s", req.ConnectionId)), false
+	}
+	defer conn.calls.Done()
+
+	outInterface, ok := conn.pending.LoadAndDelete(req.RequestId)
+	if !ok {
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.Newf(codes.FailedPrecondition, "no pending response for request: %d", req.RequestId)), false
+	}
+	out := outInterface.([]byte)
+
+	if err := conn.region.Remap(int64(req.MmapSize)); err != nil {
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, remapFailed(err)), true
+	}
+
+	return writeResponse(conn, response, req.Offset, out), true
+}
+
+// callHandler runs handler, recovering from panics so that a failing handler does not take down
+// the server. A panic is logged with its stack trace and reported to the client as Internal.
+func callHandler(ctx context.Context, handler HandlerFunc, data []byte) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if isFault(r) {
+				// The client truncated the mmap file while the handler read the request.
+				out, err = nil, status.Errorf(codes.DataLoss, "failed to read request: %v", r)
+				return
+			}
+			method, _ := Method(ctx)
+			log.Printf("panic in handler for method %s: %v\n%s", method, r, debug.Stack())
+			out, err = nil, status.Errorf(codes.Internal, "panic in handler: %v", r)
+		}
+	}()
+
+	return handler(ctx, data)
+}
+
+// guardFault recovers from a memory fault, such as a SIGBUS raised when the client truncated its
+// file, and reports it in *err as DataLoss prefixed with msg. Other panics are propagated. Faults
+// only panic in goroutines that enabled debug.SetPanicOnFault, so guardFault must be deferred with
+// its result, which it restores:
+//
+//	defer guardFault(debug.SetPanicOnFault(true), &err, "failed to read message")
+func guardFault(panicOnFault bool, err *error, msg string) {
+	debug.SetPanicOnFault(panicOnFault)
+	if r := recover(); r != nil {
+		if !isFault(r) {
+			panic(r)
+		}
+		*err = status.Errorf(codes.DataLoss, "%s: %v", msg, r)
+	}
+}
+
+// isFault reports whether the recovered value r is a memory fault turned into a panic by
+// debug.SetPanicOnFault, such as a SIGBUS raised by accessing a truncated mmap file.
+func isFault(r any) bool {
+	_, ok := r.(interface{ Addr() uintptr })
+	return ok
+}
+
+// writeResponse copies out to offset in the connection's region and completes the response.
+func writeResponse(conn *Connection, response *api.RPCResponse, offset uint64, out []byte) *api.RPCResponse {
+	if err := conn.region.Validate(); err != nil {
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.Newf(codes.DataLoss, "%v", err))
+	}
+
+	mmap := conn.region.Bytes()
+	if offset > uint64(len(mmap)) || uint64(len(out)) > uint64(len(mmap))-offset {
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.Newf(codes.InvalidArgument, "response area at offset %d exceeds mmap size %d", offset, len(mmap)))
+	}
+
+	writeLimit, err := copyResponse(mmap[offset:], out)
+	if err != nil {
+		return fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.Convert(err))
+	}
+	response.Offset = offset
 	response.Size = uint64(writeLimit)
+	response.MmapSize = uint64(len(mmap))
+
+	return response
+}
+
+// copyResponse copies out to dst, unless the handler marshaled it in place, see ResponseBuffer.
+// The file may have been truncated since it was validated, the copy then faults.
+func copyResponse(dst, out []byte) (n int, err error) {
+	defer guardFault(debug.SetPanicOnFault(true), &err, "failed to write response")
+
+	if len(out) > 0 && &out[0] != &dst[0] {
+		return copy(dst, out), nil
+	}
+	return len(out), nil
+}
 
+// fail logs the status of a failed call and stores it in the response along with the reason.
+func fail(connID string, response *api.RPCResponse, reason api.ErrorReason, st *status.Status) *api.RPCResponse {
+	log.Printf("[Connection ID: %s] %s\n", connID, st)
+	st.ToRPCResponse(response)
+	response.Reason = reason
 	return response
 }
+
+// remapFailed returns the status reported when the region cannot be remapped to the size sent by
+// the client, which is checked against the maximum size and the size of the file by Remap.
+func remapFailed(err error) *status.Status {
+	switch {
+	case errors.Is(err, region.ErrPayloadTooLarge):
+		return status.Newf(codes.InvalidArgument, "failed to remap mmap: %v", err)
+	case errors.Is(err, region.ErrTruncated):
+		return status.Newf(codes.DataLoss, "failed to remap mmap: %v", err)
+	default:
+		return status.Newf(codes.Internal, "failed to remap mmap: %v", err)
+	}
+}
+
+// payloadTooLarge returns a ResourceExhausted status with a PayloadTooLarge detail.
+func payloadTooLarge(size, maxSize uint64, format string, a ...any) *status.Status {
+	st := status.Newf(codes.ResourceExhausted, "%v: "+format, append([]any{ErrPayloadTooLarge}, a...)...)
+	if withDetails, err := st.WithDetails(&api.PayloadTooLarge{Size: size, MaxMmapSize: maxSize}); err == nil {
+		st = withDetails
+	}
+	return st
+}
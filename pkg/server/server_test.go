@@ -0,0 +1,445 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/gen/cache"
+	"github.com/epk/mmap-rpc/internal/cachetest"
+	"github.com/epk/mmap-rpc/pkg/client"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/netstringconn"
+	"github.com/epk/mmap-rpc/pkg/region"
+	"github.com/epk/mmap-rpc/pkg/server"
+	"github.com/epk/mmap-rpc/pkg/status"
+)
+
+// syncBuffer is a bytes.Buffer that may be written to from multiple goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// captureLog redirects the standard logger until the test ends.
+func captureLog(t *testing.T) *syncBuffer {
+	buf := &syncBuffer{}
+	prev := log.Writer()
+	log.SetOutput(buf)
+	t.Cleanup(func() {
+		log.SetOutput(prev)
+	})
+	return buf
+}
+
+// TestPanickingHandler checks that a panic in a handler fails the call with Internal, is logged
+// with its stack trace, and leaves the server and the connection usable.
+func TestPanickingHandler(t *testing.T) {
+	srv := &server.Server{}
+	impl := cachetest.NewServer()
+	impl.SetValue("key", "value")
+	cache.RegisterMmapRPCCacheServer(srv, impl)
+	cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv)))
+	logs := captureLog(t)
+
+	t.Run("unary", func(t *testing.T) {
+		_, err := cc.Get(context.Background(), &cache.GetRequest{Key: cachetest.PanicKey})
+		if code := status.Code(err); code != codes.Internal {
+			t.Errorf("got %v, want code Internal", err)
+		}
+		if !errors.Is(err, client.ErrHandler) {
+			t.Errorf("got %v, want ErrHandler", err)
+		}
+
+		resp, err := cc.Get(context.Background(), &cache.GetRequest{Key: "key"})
+		if err != nil || resp.Value != "value" {
+			t.Errorf("Get after the panic: got %v, %v", resp, err)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		stream, err := cc.Watch(context.Background(), &cache.WatchRequest{Keys: []string{cachetest.PanicKey}})
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.Internal {
+			t.Errorf("got %v, want code Internal", err)
+		}
+
+		stream, err = cc.Watch(context.Background(), &cache.WatchRequest{Keys: []string{"key"}})
+		if err != nil {
+			t.Fatalf("Watch after the panic: %v", err)
+		}
+		if event, err := stream.Recv(); err != nil || event.Key != "key" {
+			t.Errorf("Watch after the panic: got %v, %v", event, err)
+		}
+	})
+
+	for _, method := range []string{"Get", "Watch"} {
+		msg := "panic in handler for method /cache.Cache/" + method + ": " + method + " panicked"
+		if !strings.Contains(logs.String(), msg) {
+			t.Errorf("log does not contain %q:\n%s", msg, logs)
+		}
+	}
+	if !strings.Contains(logs.String(), "runtime/debug.Stack") {
+		t.Errorf("log does not contain the stack trace:\n%s", logs)
+	}
+}
+
+// TestZeroStreamWindow checks that a stream whose request takes the whole region, leaving no room
+// for a window, can still send empty messages.
+func TestZeroStreamWindow(t *testing.T) {
+	const maxMmapSize = 4096
+	srv := &server.Server{MaxMmapSize: maxMmapSize}
+	cache.RegisterMmapRPCCacheServer(srv, cachetest.NewServer())
+	cc := cache.NewMmapRPCCacheClient(cachetest.Dial(t, cachetest.Serve(t, srv)))
+
+	req := &cache.WatchRequest{Keys: []string{""}}
+	req.Keys = append(req.Keys, strings.Repeat("x", maxMmapSize-proto.Size(req)-3))
+	if size := proto.Size(req); size != maxMmapSize {
+		t.Fatalf("got request size %d, want %d", size, maxMmapSize)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := cc.Watch(ctx, req)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if event, err := stream.Recv(); err != nil || event.Key != "" {
+		t.Errorf("got %v, %v, want the event of the empty key", event, err)
+	}
+}
+
+// TestShutdown checks that Shutdown tells the clients to stop making calls and waits for the calls
+// in flight, up to the deadline of its context after which they are canceled.
+func TestShutdown(t *testing.T) {
+	t.Run("drain", func(t *testing.T) {
+		srv := &server.Server{}
+		started, release := make(chan struct{}), make(chan struct{})
+		srv.RegisterHandler("/test.Test/Block", func(ctx context.Context, data []byte) ([]byte, error) {
+			close(started)
+			<-release
+			return proto.Marshal(&cache.GetResponse{Value: "done"})
+		})
+		c := cachetest.Dial(t, cachetest.Serve(t, srv))
+
+		called := make(chan error, 1)
+		go func() {
+			resp := &cache.GetResponse{}
+			err := c.Invoke(context.Background(), "/test.Test/Block", &cache.GetRequest{}, resp)
+			if err == nil && resp.Value != "done" {
+				err = fmt.Errorf("got value %q, want %q", resp.Value, "done")
+			}
+			called <- err
+		}()
+		<-started
+
+		shutdown := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			shutdown <- srv.Shutdown(ctx)
+		}()
+
+		// Calls made before the GoAway arrives are rejected by the server.
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			err := c.Invoke(context.Background(), "/test.Test/Block", &cache.GetRequest{}, &cache.GetResponse{})
+			if errors.Is(err, client.ErrServerShutdown) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("got %v after Shutdown, want ErrServerShutdown", err)
+			}
+		}
+		select {
+		case err := <-shutdown:
+			t.Fatalf("Shutdown returned %v while a call was in flight", err)
+		default:
+		}
+
+		close(release)
+		if err := <-called; err != nil {
+			t.Errorf("call in flight: %v", err)
+		}
+		if err := <-shutdown; err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		srv := &server.Server{}
+		calls, release := make(chan context.Context, 1), make(chan struct{})
+		defer close(release)
+		srv.RegisterHandler("/test.Test/Block", func(ctx context.Context, data []byte) ([]byte, error) {
+			calls <- ctx
+			<-release
+			return nil, ctx.Err()
+		})
+		c := cachetest.Dial(t, cachetest.Serve(t, srv))
+
+		go c.Invoke(context.Background(), "/test.Test/Block", &cache.GetRequest{}, &cache.GetResponse{})
+		callCtx := <-calls
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want context.DeadlineExceeded", err)
+		}
+		select {
+		case <-callCtx.Done():
+		case <-time.After(5 * time.Second):
+			t.Error("the call in flight was not canceled")
+		}
+	})
+}
+
+// TestDroppedSocket checks that the connections of a client that closes its socket without
+// disconnecting are torn down.
+func TestDroppedSocket(t *testing.T) {
+	dir := t.TempDir()
+	srv := &server.Server{MmapFilePrefix: dir + string(filepath.Separator)}
+	socketPath := cachetest.Serve(t, srv)
+
+	for range 100 {
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		nc := netstringconn.NewNetstringConn(conn)
+
+		request, err := anypb.New(&api.ConnectRequest{})
+		if err != nil {
+			t.Fatalf("failed to wrap connect request: %v", err)
+		}
+		data, err := proto.Marshal(request)
+		if err != nil {
+			t.Fatalf("failed to marshal connect request: %v", err)
+		}
+		if err := nc.Write(data); err != nil {
+			t.Fatalf("failed to send connect request: %v", err)
+		}
+		if _, err := nc.Read(); err != nil {
+			t.Fatalf("failed to read connect response: %v", err)
+		}
+		nc.Close()
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.mmap"))
+		if len(files) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d mmap files left after the sockets were closed", len(files))
+		}
+	}
+}
+
+// TestTruncatedRegion checks that a client truncating its mmap file while a handler reads the
+// request fails the call with DataLoss instead of crashing the server.
+func TestTruncatedRegion(t *testing.T) {
+	dir := t.TempDir()
+	srv := &server.Server{MmapFilePrefix: dir + string(filepath.Separator)}
+	impl := cachetest.NewServer()
+	impl.SetValue("key", "value")
+	cache.RegisterMmapRPCCacheServer(srv, impl)
+	started, truncated := make(chan struct{}), make(chan struct{})
+	srv.RegisterHandler("/test.Test/Sum", func(ctx context.Context, data []byte) ([]byte, error) {
+		close(started)
+		<-truncated
+		var sum byte
+		for _, b := range data {
+			sum += b
+		}
+		return []byte{sum}, nil
+	})
+	c := cachetest.Dial(t, cachetest.Serve(t, srv))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.mmap"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got mmap files %v, %v, want one", files, err)
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatalf("failed to stat mmap file: %v", err)
+	}
+
+	go func() {
+		<-started
+		if err := os.Truncate(files[0], 0); err != nil {
+			t.Errorf("failed to truncate mmap file: %v", err)
+		}
+		close(truncated)
+	}()
+	req := &cache.GetRequest{Key: strings.Repeat("x", 64*1024)}
+	err = c.Invoke(context.Background(), "/test.Test/Sum", req, &cache.GetResponse{})
+	if code := status.Code(err); code != codes.DataLoss {
+		t.Errorf("got %v, want code DataLoss", err)
+	}
+
+	// The server keeps serving once the file is restored.
+	if err := os.Truncate(files[0], info.Size()); err != nil {
+		t.Fatalf("failed to restore mmap file: %v", err)
+	}
+	resp, err := cache.NewMmapRPCCacheClient(c).Get(context.Background(), &cache.GetRequest{Key: "key"})
+	if err != nil || resp.Value != "value" {
+		t.Errorf("Get after the truncation: got %v, %v", resp, err)
+	}
+}
+
+// TestGuardFault reads a truncated mapping from a goroutine that did not enable panics on faults,
+// such as one started by a handler to send stream messages. The fault must fail the read with
+// DataLoss instead of crashing the server.
+func TestGuardFault(t *testing.T) {
+	r, err := region.Create(filepath.Join(t.TempDir(), "region.mmap"), int64(os.Getpagesize()), 0o600)
+	if err != nil {
+		t.Fatalf("failed to create region: %v", err)
+	}
+	defer r.Close()
+	if err := os.Truncate(r.Name(), 0); err != nil {
+		t.Fatalf("failed to truncate mmap file: %v", err)
+	}
+	mmap := r.Bytes()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- func() (err error) {
+			defer server.GuardFault(debug.SetPanicOnFault(true), &err, "failed to read")
+			if mmap[0] != 0 {
+				return errors.New("read a byte past the end of the file")
+			}
+			return nil
+		}()
+	}()
+	if err := <-errc; status.Code(err) != codes.DataLoss {
+		t.Errorf("got %v, want code DataLoss", err)
+	}
+}
+
+// TestMmapDirChecks checks that the server refuses to create mmap files in a directory where
+// other users could tamper with them.
+func TestMmapDirChecks(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  os.FileMode
+		chown bool
+		ok    bool
+	}{
+		{name: "private", mode: 0o700, ok: true},
+		{name: "readable", mode: 0o755, ok: true},
+		{name: "group-writable", mode: 0o770},
+		{name: "world-writable", mode: 0o707},
+		{name: "owned by another user", mode: 0o700, chown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "mmap")
+			if err := os.Mkdir(dir, tt.mode); err != nil {
+				t.Fatalf("failed to create directory: %v", err)
+			}
+			// The umask may have cleared bits of the mode.
+			if err := os.Chmod(dir, tt.mode); err != nil {
+				t.Fatalf("failed to chmod directory: %v", err)
+			}
+			if tt.chown {
+				if os.Geteuid() != 0 {
+					t.Skip("changing the owner of the directory requires root")
+				}
+				if err := os.Chown(dir, 65534, 65534); err != nil {
+					t.Fatalf("failed to chown directory: %v", err)
+				}
+			}
+
+			srv := &server.Server{MmapFilePrefix: dir + string(filepath.Separator)}
+			if tt.ok {
+				// The mmap file of the connection is created in the directory.
+				cachetest.Dial(t, cachetest.Serve(t, srv))
+				return
+			}
+
+			listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "mmap-rpc.sock"))
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			done := make(chan error, 1)
+			go func() {
+				done <- srv.Serve(listener)
+			}()
+			select {
+			case err := <-done:
+				if err == nil || errors.Is(err, server.ErrServerClosed) {
+					t.Errorf("got %v, want the directory to be rejected", err)
+				}
+			case <-time.After(time.Second):
+				srv.Close()
+				<-done
+				t.Errorf("the server accepted the directory")
+			}
+		})
+	}
+}
+
+// BenchmarkHandleRequest compares the two ways a unary response gets into shared memory: marshaled
+// into a new slice and copied when the client reserved no area for it, and marshaled in place by
+// HandleRequest when it did.
+func BenchmarkHandleRequest(b *testing.B) {
+	for _, size := range []int{64, 4096, 64 * 1024} {
+		resp := &cache.GetResponse{Value: strings.Repeat("x", size), Found: true}
+		handler := func(context.Context, *cache.GetRequest) (*cache.GetResponse, error) { return resp, nil }
+		data, err := proto.Marshal(&cache.GetRequest{Key: "key"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		// dst stands in for the area of the response in the memory-mapped file.
+		dst := make([]byte, proto.Size(resp))
+
+		run := func(b *testing.B, ctx context.Context) {
+			b.ReportAllocs()
+			for range b.N {
+				out, err := server.HandleRequest(ctx, data, handler, &cache.GetRequest{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := server.CopyResponse(dst, out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.Run(fmt.Sprintf("copy/%d", size), func(b *testing.B) {
+			run(b, context.Background())
+		})
+		b.Run(fmt.Sprintf("in_place/%d", size), func(b *testing.B) {
+			run(b, server.WithResponseBuffer(context.Background(), dst))
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/codes"
+	"github.com/epk/mmap-rpc/pkg/netstringconn"
+	"github.com/epk/mmap-rpc/pkg/status"
+)
+
+// StreamHandlerFunc handles a streaming call. For server-streaming methods, data is the serialized
+// request, with the aliasing rules of RawRequest; otherwise it is nil and the handler receives the
+// messages of the client with stream.Recv. The handler sends its messages with stream.Send. The
+// error returned by the handler is the trailer of the call, the stream ends successfully if it is
+// nil.
+type StreamHandlerFunc func(ctx context.Context, data []byte, stream *ServerStream) error
+
+// StreamDesc describes a streaming method.
+type StreamDesc struct {
+	MethodName string
+	Handler    StreamHandlerFunc
+	// ServerStreams and ClientStreams report whether the server and the client send a stream of
+	// messages. A method where only the client streams responds with a single message, which the
+	// handler sends before it returns.
+	ServerStreams bool
+	ClientStreams bool
+}
+
+// RegisterStream registers the handler of a streaming method. Unary interceptors are not run
+// around streaming calls.
+func (s *Server) RegisterStream(desc *StreamDesc) {
+	s.implsStubs.Store(desc.MethodName, desc)
+}
+
+// ServerStream is the server side of a streaming call. Neither Send nor Recv may be called
+// concurrently with itself, nor once the handler returned, but one goroutine may send while
+// another one receives.
+//
+// Messages are written to a window of the memory-mapped file reserved by the client, and Send
+// blocks while the client has not read enough of the previous messages to make room for the next
+// one, or until the context of the call is done.
+type ServerStream struct {
+	ctx       context.Context
+	s         *Server
+	w         *netstringconn.NetstringConn
+	conn      *Connection
+	requestID uint64
+	handler   StreamHandlerFunc
+	data      []byte
+	// response is the trailer of the call.
+	response *api.RPCResponse
+	// clientStreams is set if the client sends a stream of messages.
+	clientStreams bool
+
+	// acked is signaled whenever the client acknowledged messages or replaced the window, and
+	// received whenever the client sent a frame.
+	acked    chan struct{}
+	received chan struct{}
+
+	// mu guards the fields below, which are updated by the StreamAck messages of the client.
+	mu sync.Mutex
+	// offset and capacity locate the window in the region, mmapSize is the size of the region
+	// the client last reported.
+	offset   uint64
+	capacity uint64
+	mmapSize uint64
+	// head and tail are the positions of the oldest unread message and of the end of the newest
+	// message, counted in bytes written to the window since it was set, and spans holds the
+	// space taken by each unread message, including the padding before it.
+	head  uint64
+	tail  uint64
+	spans []uint64
+	// requested is set once the client was asked for a larger window.
+	requested bool
+	// frames holds the frames sent by the client and not received yet, and closeSent is set once
+	// the client sent its last message.
+	frames    []*api.StreamFrame
+	closeSent bool
+}
+
+// frameAlign is the alignment of the messages in the window.
+const frameAlign = 8
+
+// Context returns the context of the call.
+func (ss *ServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+// Send marshals m into the window of the stream and notifies the client. It returns an error once
+// the context of the call is done, or if the message does not fit in the maximum mmap size.
+func (ss *ServerStream) Send(m proto.Message) error {
+	size := uint64(proto.Size(m))
+	if int64(size) > ss.conn.region.MaxSize() {
+		return payloadTooLarge(size, uint64(ss.conn.region.MaxSize()),
+			"stream message size %d exceeds maximum mmap size %d", size, ss.conn.region.MaxSize()).Err()
+	}
+
+	offset, mmapSize, err := ss.reserve(size)
+	if err != nil {
+		return err
+	}
+	if err := ss.write(offset, mmapSize, size, m); err != nil {
+		return err
+	}
+
+	return ss.s.send(ss.w, &api.StreamFrame{
+		ConnectionId: ss.conn.id,
+		RequestId:    ss.requestID,
+		Offset:       offset,
+		Size:         size,
+		MmapSize:     mmapSize,
+	})
+}
+
+// Recv reads the next message sent by the client into m, and lets the client reuse its space. It
+// returns io.EOF once the client sent its last message, and right away for server-streaming methods.
+// It returns an error once the context of the call is done.
+func (ss *ServerStream) Recv(m proto.Message) error {
+	if !ss.clientStreams {
+		return io.EOF
+	}
+
+	frame, err := ss.nextFrame()
+	if err != nil {
+		return err
+	}
+	if err := ss.read(frame, m); err != nil {
+		return err
+	}
+	return ss.s.send(ss.w, &api.StreamAck{
+		ConnectionId: ss.conn.id,
+		RequestId:    ss.requestID,
+		Messages:     1,
+	})
+}
+
+// nextFrame waits for the next frame sent by the client.
+func (ss *ServerStream) nextFrame() (*api.StreamFrame, error) {
+	for {
+		ss.mu.Lock()
+		if len(ss.frames) > 0 {
+			frame := ss.frames[0]
+			ss.frames = ss.frames[1:]
+			ss.mu.Unlock()
+			return frame, nil
+		}
+		closeSent := ss.closeSent
+		ss.mu.Unlock()
+		if closeSent {
+			return nil, io.EOF
+		}
+
+		select {
+		case <-ss.received:
+		case <-ss.ctx.Done():
+			return nil, ss.ctx.Err()
+		}
+	}
+}
+
+// read unmarshals the message of frame into m.
+func (ss *ServerStream) read(frame *api.StreamFrame, m proto.Message) (err error) {
+	region := ss.conn.region
+	// The client grows the region when the message does not fit.
+	if err := region.Remap(int64(frame.MmapSize)); err != nil {
+		return remapFailed(err).Err()
+	}
+	if err := region.Validate(); err != nil {
+		return status.Errorf(codes.DataLoss, "%v", err)
+	}
+
+	mmap := region.Bytes()
+	if frame.Offset > uint64(len(mmap)) || frame.Size > uint64(len(mmap))-frame.Offset {
+		return status.Errorf(codes.InvalidArgument, "stream message at offset %d with size %d exceeds mmap size %d", frame.Offset, frame.Size, len(mmap))
+	}
+
+	// The file may still be truncated after it was validated, the read then faults.
+	defer guardFault(debug.SetPanicOnFault(true), &err, "failed to read stream message")
+
+	if err := proto.Unmarshal(mmap[frame.Offset:frame.Offset+frame.Size], m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to unmarshal stream message: %v", err)
+	}
+	return nil
+}
+
+// push queues a frame sent by the client.
+func (ss *ServerStream) push(frame *api.StreamFrame) {
+	ss.mu.Lock()
+	if ss.closeSent {
+		ss.mu.Unlock()
+		return
+	}
+	if frame.CloseSend {
+		ss.closeSent = true
+	} else {
+		ss.frames = append(ss.frames, frame)
+	}
+	ss.mu.Unlock()
+
+	select {
+	case ss.received <- struct{}{}:
+	default:
+	}
+}
+
+// reserve waits until the window has room for a message of the given size and returns where to
+// write it, along with the size of the region the window is in.
+func (ss *ServerStream) reserve(size uint64) (offset, mmapSize uint64, err error) {
+	need := (size + frameAlign - 1) &^ (frameAlign - 1)
+	for {
+		ss.mu.Lock()
+		if need == 0 {
+			// Empty messages take no room, the window may have none.
+			ss.spans = append(ss.spans, 0)
+			offset, mmapSize = ss.offset, ss.mmapSize
+			ss.mu.Unlock()
+			return offset, mmapSize, nil
+		}
+		if need > ss.capacity {
+			requested := ss.requested
+			ss.requested = true
+			ss.mu.Unlock()
+
+			if !requested {
+				// The client provides a larger window once it read the messages in the current one.
+				if err := ss.s.send(ss.w, &api.StreamFrame{
+					ConnectionId: ss.conn.id,
+					RequestId:    ss.requestID,
+					Size:         size,
+					Pending:      true,
+				}); err != nil {
+					return 0, 0, err
+				}
+			}
+		} else {
+			if ss.head == ss.tail {
+				// The window is empty, messages that fit in it must not wait for the padding at its end.
+				ss.head, ss.tail = 0, 0
+			}
+			at := ss.tail % ss.capacity
+			var pad uint64
+			if ss.capacity-at < need {
+				// The message does not fit before the end of the window, it starts over at the beginning.
+				pad = ss.capacity - at
+				at = 0
+			}
+			if pad+need <= ss.capacity-(ss.tail-ss.head) {
+				ss.tail += pad + need
+				ss.spans = append(ss.spans, pad+need)
+				offset, mmapSize = ss.offset+at, ss.mmapSize
+				ss.mu.Unlock()
+				return offset, mmapSize, nil
+			}
+			ss.mu.Unlock()
+		}
+
+		select {
+		case <-ss.acked:
+		case <-ss.ctx.Done():
+			return 0, 0, ss.ctx.Err()
+		}
+	}
+}
+
+// write marshals m into the region at offset.
+func (ss *ServerStream) write(offset, mmapSize, size uint64, m proto.Message) (err error) {
+	region := ss.conn.region
+	if err := region.Remap(int64(mmapSize)); err != nil {
+		return remapFailed(err).Err()
+	}
+	if err := region.Validate(); err != nil {
+		return status.Errorf(codes.DataLoss, "%v", err)
+	}
+
+	mmap := region.Bytes()
+	if offset > uint64(len(mmap)) || size > uint64(len(mmap))-offset {
+		return status.Errorf(codes.InvalidArgument, "stream window at offset %d exceeds mmap size %d", offset, len(mmap))
+	}
+
+	// The file may still be truncated after it was validated, the write then faults.
+	defer guardFault(debug.SetPanicOnFault(true), &err, "failed to write stream message")
+
+	out, err := proto.MarshalOptions{UseCachedSize: true}.MarshalAppend(mmap[offset:offset:offset+size], m)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to marshal stream message: %v", err)
+	}
+	if uint64(len(out)) != size {
+		return status.Error(codes.Internal, "stream message changed while it was marshaled")
+	}
+	return nil
+}
+
+// ack releases the messages read by the client and applies a new window.
+func (ss *ServerStream) ack(req *api.StreamAck) {
+	ss.mu.Lock()
+	for n := req.Messages; n > 0 && len(ss.spans) > 0; n-- {
+		ss.head += ss.spans[0]
+		ss.spans = ss.spans[1:]
+	}
+	if req.WindowCapacity > 0 {
+		ss.offset, ss.capacity = req.WindowOffset, req.WindowCapacity
+		ss.head, ss.tail, ss.spans = 0, 0, nil
+		ss.requested = false
+	}
+	ss.mmapSize = max(ss.mmapSize, req.MmapSize)
+	ss.mu.Unlock()
+
+	select {
+	case ss.acked <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) handleStreamFrame(w *netstringconn.NetstringConn, req *api.StreamFrame) {
+	conn, ok := s.connection(w, req.ConnectionId)
+	if !ok {
+		return
+	}
+	if stream, ok := conn.streams.Load(req.RequestId); ok {
+		stream.(*ServerStream).push(req)
+	}
+}
+
+func (s *Server) handleStreamAck(w *netstringconn.NetstringConn, req *api.StreamAck) {
+	conn, ok := s.connection(w, req.ConnectionId)
+	if !ok {
+		return
+	}
+	if stream, ok := conn.streams.Load(req.RequestId); ok {
+		stream.(*ServerStream).ack(req)
+	}
+}
+
+// openStream sets up the stream opened by req, or returns its failed trailer. The stream is
+// registered with its connection until it is done, see handleStream.
+func (s *Server) openStream(ctx context.Context, w *netstringconn.NetstringConn, req *api.RPCRequest) (*ServerStream, *api.RPCResponse) {
+	response := &api.RPCResponse{
+		ConnectionId:             req.ConnectionId,
+		FullyQualifiedMethodName: req.FullyQualifiedMethodName,
+		RequestId:                req.RequestId,
+	}
+
+	conn, ok := s.connection(w, req.ConnectionId)
+	if !ok || !conn.begin() {
+		return nil, fail(req.ConnectionId, response, api.ErrorReason_ERROR_REASON_CONNECTION_NOT_FOUND, status.Newf(codes.FailedPrecondition, "connection not found: %s", req.ConnectionId))
+	}
+
+	stream, failed := s.newStream(ctx, w, conn, req, response)
+	if failed != nil {
+		conn.calls.Done()
+		return nil, failed
+	}
+	conn.streams.Store(req.RequestId, stream)
+	return stream, nil
+}
+
+// newStream looks up the handler of the stream opened by req and reads its request.
+func (s *Server) newStream(ctx context.Context, w *netstringconn.NetstringConn, conn *Connection, req *api.RPCRequest, response *api.RPCResponse) (*ServerStream, *api.RPCResponse) {
+	handlerInterface, ok := s.implsStubs.Load(req.FullyQualifiedMethodName)
+	if !ok {
+		return nil, fail(conn.id, response, api.ErrorReason_ERROR_REASON_METHOD_NOT_FOUND, status.Newf(codes.Unimplemented, "method not found: %s", req.FullyQualifiedMethodName))
+	}
+	desc, ok := handlerInterface.(*StreamDesc)
+	if !ok {
+		return nil, fail(conn.id, response, api.ErrorReason_ERROR_REASON_UNSPECIFIED, status.Newf(codes.Unimplemented, "method %s is not a streaming method", req.FullyQualifiedMethodName))
+	}
+
+	mmap, data, failed := readRequest(conn, req, response)
+	if failed != nil {
+		return nil, failed
+	}
+	if desc.ClientStreams {
+		data = nil
+	}
+
+	return &ServerStream{
+		ctx:           context.WithValue(ctx, rawRequestKey, data),
+		s:             s,
+		w:             w,
+		conn:          conn,
+		requestID:     req.RequestId,
+		handler:       desc.Handler,
+		data:          data,
+		response:      response,
+		clientStreams: desc.ClientStreams,
+		acked:         make(chan struct{}, 1),
+		received:      make(chan struct{}, 1),
+		offset:        req.ResponseOffset,
+		capacity:      req.ResponseCapacity,
+		mmapSize:      uint64(len(mmap)),
+	}, nil
+}
+
+// handleStream runs the handler of stream and returns its trailer.
+func (s *Server) handleStream(stream *ServerStream) *api.RPCResponse {
+	conn := stream.conn
+	defer conn.calls.Done()
+	defer conn.streams.Delete(stream.requestID)
+
+	if err := callStreamHandler(stream.ctx, stream.handler, stream.data, stream); err != nil {
+		return fail(conn.id, stream.response, api.ErrorReason_ERROR_REASON_HANDLER, status.Convert(err))
+	}
+	return stream.response
+}
+
+// callStreamHandler is like callHandler for streaming calls.
+func callStreamHandler(ctx context.Context, handler StreamHandlerFunc, data []byte, stream *ServerStream) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if isFault(r) {
+				err = status.Errorf(codes.DataLoss, "failed to read request: %v", r)
+				return
+			}
+			method, _ := Method(ctx)
+			log.Printf("panic in handler for method %s: %v\n%s", method, r, debug.Stack())
+			err = status.Errorf(codes.Internal, "panic in handler: %v", r)
+		}
+	}()
+
+	return handler(ctx, data, stream)
+}
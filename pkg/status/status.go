@@ -0,0 +1,167 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/epk/mmap-rpc/gen/api"
+	"github.com/epk/mmap-rpc/pkg/codes"
+)
+
+// Status is the outcome of an RPC: a code, a message and optional details.
+// Handlers return it as an error to send a specific code to the client, and
+// clients receive it from the generated stubs.
+type Status struct {
+	code    codes.Code
+	message string
+	details []*anypb.Any
+}
+
+// New returns a Status with the given code and message.
+func New(c codes.Code, msg string) *Status {
+	return &Status{code: c, message: msg}
+}
+
+// Newf returns a Status with the given code and formatted message.
+func Newf(c codes.Code, format string, a ...any) *Status {
+	return New(c, fmt.Sprintf(format, a...))
+}
+
+// Error returns an error with the given code and message.
+func Error(c codes.Code, msg string) error {
+	return New(c, msg).Err()
+}
+
+// Errorf returns an error with the given code and formatted message.
+func Errorf(c codes.Code, format string, a ...any) error {
+	return Newf(c, format, a...).Err()
+}
+
+// Code returns the status code, OK for a nil Status.
+func (s *Status) Code() codes.Code {
+	if s == nil {
+		return codes.OK
+	}
+	return s.code
+}
+
+// Message returns the status message.
+func (s *Status) Message() string {
+	if s == nil {
+		return ""
+	}
+	return s.message
+}
+
+// WithDetails returns a copy of s with the given messages attached as details.
+func (s *Status) WithDetails(details ...proto.Message) (*Status, error) {
+	if s.Code() == codes.OK {
+		return nil, errors.New("no error details for status with code OK")
+	}
+
+	out := &Status{code: s.code, message: s.message, details: append([]*anypb.Any(nil), s.details...)}
+	for _, detail := range details {
+		any, err := anypb.New(detail)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create any: %w", err)
+		}
+		out.details = append(out.details, any)
+	}
+	return out, nil
+}
+
+// Details returns the details attached to the status. Details whose type is not linked into the
+// binary are returned as an error instead.
+func (s *Status) Details() []any {
+	if s == nil {
+		return nil
+	}
+
+	details := make([]any, 0, len(s.details))
+	for _, any := range s.details {
+		detail, err := any.UnmarshalNew()
+		if err != nil {
+			details = append(details, err)
+			continue
+		}
+		details = append(details, detail)
+	}
+	return details
+}
+
+// Err returns an error representing s, nil if the code is OK.
+func (s *Status) Err() error {
+	if s.Code() == codes.OK {
+		return nil
+	}
+	return &statusError{s: s}
+}
+
+// String implements fmt.Stringer.
+func (s *Status) String() string {
+	return fmt.Sprintf("rpc error: code = %s desc = %s", s.Code(), s.Message())
+}
+
+type statusError struct {
+	s *Status
+}
+
+func (e *statusError) Error() string {
+	return e.s.String()
+}
+
+// FromError returns the Status represented by err, which may wrap an error returned by Error,
+// Errorf or Status.Err. Context errors are converted to Canceled and DeadlineExceeded.
+// Otherwise it returns a Status with code Unknown and false.
+func FromError(err error) (*Status, bool) {
+	if err == nil {
+		return nil, true
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.s, true
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return New(codes.Canceled, err.Error()), true
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(codes.DeadlineExceeded, err.Error()), true
+	}
+
+	return New(codes.Unknown, err.Error()), false
+}
+
+// Convert is like FromError but discards the boolean.
+func Convert(err error) *Status {
+	s, _ := FromError(err)
+	return s
+}
+
+// Code returns the status code of err, OK if err is nil.
+func Code(err error) codes.Code {
+	return Convert(err).Code()
+}
+
+// FromRPCResponse returns the status reported by the server in response.
+func FromRPCResponse(response *api.RPCResponse) *Status {
+	c := codes.Code(response.Code)
+	if c == codes.OK && response.Error != "" {
+		c = codes.Unknown
+	}
+	return &Status{code: c, message: response.Error, details: response.Details}
+}
+
+// ToRPCResponse stores s in response.
+func (s *Status) ToRPCResponse(response *api.RPCResponse) {
+	response.Code = api.Code(s.Code())
+	response.Error = s.Message()
+	if s != nil {
+		response.Details = s.details
+	}
+}